@@ -13,6 +13,10 @@ type Config struct {
 	AppsDir       string
 	NodeEnv       string
 	UseDocker     bool
+	Runtime       string // "docker" (default) or "podman"; selects the ContainerRuntime backend when UseDocker is set
+	RegistryURL   string // e.g. "registry.thakur.dev"; empty means local-only images
+	RegistryAuth  string // optional static "user:pass" override; falls back to credential helpers
+	MACProfile    string // AppArmor profile name or SELinux "type:..." label enforced on all user workloads
 }
 
 var cfg *Config
@@ -30,6 +34,10 @@ func Load() *Config {
 		AppsDir:       getEnv("APPS_DIR", "./apps"),
 		NodeEnv:       getEnv("NODE_ENV", "development"),
 		UseDocker:     getEnv("USE_DOCKER", "false") == "true",
+		Runtime:       getEnv("RUNTIME", "docker"),
+		RegistryURL:   getEnv("REGISTRY_URL", ""),
+		RegistryAuth:  getEnv("REGISTRY_AUTH", ""),
+		MACProfile:    getEnv("MAC_PROFILE", ""),
 	}
 
 	return cfg
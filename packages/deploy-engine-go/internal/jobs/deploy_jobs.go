@@ -0,0 +1,165 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/thakurdotdev/deploy-engine/internal/services"
+)
+
+// Sentinel errors for the deployment activation pipeline, so a failed step
+// is machine-readable (e.g. {"error":"ErrStartAppFailed"}) instead of a
+// free-form string.
+var (
+	ErrReceiveArtifactFailed = errors.New("ErrReceiveArtifactFailed")
+	ErrExtractArtifactFailed = errors.New("ErrExtractArtifactFailed")
+	ErrInstallDepsFailed     = errors.New("ErrInstallDepsFailed")
+	ErrStartAppFailed        = errors.New("ErrStartAppFailed")
+	ErrHealthCheckFailed     = errors.New("ErrHealthCheckFailed")
+)
+
+// pipelineState threads results between an activation's steps: later steps
+// need the build directory ExtractArtifact produced and the resolved
+// "current" target ActivateBuild pointed it at, and dep is the in-flight
+// handle registered by receiveArtifactJob for Shutdown to drain.
+type pipelineState struct {
+	req        services.ActivateRequest
+	currentDir string
+	dep        *services.Deployment
+}
+
+// receiveArtifactJob is also this pipeline's Finalizer: it's always the
+// first step Queue.Submit runs, so it's the natural place to register the
+// pipeline as in-flight, and Finalize (run once the pipeline ends,
+// regardless of which step failed) is the natural place to release it.
+type receiveArtifactJob struct {
+	state  *pipelineState
+	deploy *services.DeployService
+}
+
+func (j *receiveArtifactJob) Name() string { return "ReceiveArtifact" }
+
+func (j *receiveArtifactJob) Execute(resp *Response) {
+	dep, err := j.deploy.BeginActivation(j.state.req)
+	if err != nil {
+		resp.Failure(fmt.Errorf("%w: %v", ErrReceiveArtifactFailed, err))
+		return
+	}
+	j.state.dep = dep
+
+	if _, err := j.deploy.VerifyArtifact(j.state.req.BuildID); err != nil {
+		resp.Failure(fmt.Errorf("%w: %v", ErrReceiveArtifactFailed, err))
+		return
+	}
+	resp.Success()
+}
+
+// Finalize releases this pipeline's in-flight slot once it reaches a
+// terminal state. It's a no-op if BeginActivation itself failed (the
+// engine was already draining), since nothing was registered in that case.
+func (j *receiveArtifactJob) Finalize() {
+	if j.state.dep != nil {
+		j.deploy.EndActivation(j.state.dep)
+	}
+}
+
+type extractArtifactJob struct {
+	state  *pipelineState
+	deploy *services.DeployService
+}
+
+func (j *extractArtifactJob) Name() string { return "ExtractArtifact" }
+
+func (j *extractArtifactJob) Execute(resp *Response) {
+	buildDir, err := j.deploy.ExtractBuild(j.state.req)
+	if err != nil {
+		resp.Failure(fmt.Errorf("%w: %v", ErrExtractArtifactFailed, err))
+		return
+	}
+
+	currentDir, err := j.deploy.ActivateBuild(j.state.req, buildDir)
+	if err != nil {
+		resp.Failure(fmt.Errorf("%w: %v", ErrExtractArtifactFailed, err))
+		return
+	}
+
+	j.state.currentDir = currentDir
+	resp.SuccessWithData(currentDir)
+}
+
+type installDepsJob struct {
+	state  *pipelineState
+	deploy *services.DeployService
+}
+
+func (j *installDepsJob) Name() string { return "InstallDeps" }
+
+func (j *installDepsJob) Execute(resp *Response) {
+	if err := j.deploy.InstallDeps(j.state.req, j.state.currentDir); err != nil {
+		resp.Failure(fmt.Errorf("%w: %v", ErrInstallDepsFailed, err))
+		return
+	}
+	resp.Success()
+}
+
+type startAppJob struct {
+	state  *pipelineState
+	deploy *services.DeployService
+}
+
+func (j *startAppJob) Name() string { return "StartApp" }
+
+func (j *startAppJob) Execute(resp *Response) {
+	if err := j.deploy.StartApp(j.state.req, j.state.currentDir); err != nil {
+		j.deploy.RecoverFailedStart(j.state.req)
+		resp.Failure(fmt.Errorf("%w: %v", ErrStartAppFailed, err))
+		return
+	}
+	resp.Success()
+}
+
+type healthCheckJob struct {
+	state  *pipelineState
+	deploy *services.DeployService
+}
+
+func (j *healthCheckJob) Name() string { return "HealthCheck" }
+
+func (j *healthCheckJob) Execute(resp *Response) {
+	if err := j.deploy.HealthCheck(j.state.req); err != nil {
+		j.deploy.RecoverFailedStart(j.state.req)
+		resp.Failure(fmt.Errorf("%w: %v", ErrHealthCheckFailed, err))
+		return
+	}
+	resp.Success()
+}
+
+type configureNginxJob struct {
+	state  *pipelineState
+	deploy *services.DeployService
+}
+
+func (j *configureNginxJob) Name() string { return "ConfigureNginx" }
+
+func (j *configureNginxJob) Execute(resp *Response) {
+	// Nginx config failures are non-fatal - logged as a warning, not a
+	// deployment failure.
+	if err := j.deploy.ConfigureNginx(j.state.req); err != nil {
+		services.StreamLog(j.state.req.BuildID, fmt.Sprintf("Failed to configure Nginx: %v", err), services.LogLevelWarning)
+	}
+	resp.Success()
+}
+
+// NewActivationPipeline builds the ordered steps for one ActivateRequest,
+// ready to hand to Queue.Submit.
+func NewActivationPipeline(deploy *services.DeployService, req services.ActivateRequest) []Job {
+	state := &pipelineState{req: req}
+	return []Job{
+		&receiveArtifactJob{state: state, deploy: deploy},
+		&extractArtifactJob{state: state, deploy: deploy},
+		&installDepsJob{state: state, deploy: deploy},
+		&startAppJob{state: state, deploy: deploy},
+		&healthCheckJob{state: state, deploy: deploy},
+		&configureNginxJob{state: state, deploy: deploy},
+	}
+}
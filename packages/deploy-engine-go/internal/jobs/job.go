@@ -0,0 +1,57 @@
+// Package jobs models a deployment activation as a sequence of small,
+// independently retriable steps instead of one long synchronous call, and
+// runs them through a bounded, per-project-serialized queue so the HTTP
+// layer can hand back a job ID immediately instead of blocking a request
+// for the life of the deployment.
+package jobs
+
+// Status is a job's current lifecycle state.
+type Status string
+
+const (
+	StatusQueued  Status = "queued"
+	StatusRunning Status = "running"
+	StatusSuccess Status = "success"
+	StatusFailure Status = "failure"
+)
+
+// Response is how a Job reports its outcome. A Job must call exactly one
+// of Success, SuccessWithData, or Failure before Execute returns.
+type Response struct {
+	status Status
+	data   interface{}
+	err    error
+}
+
+// Success marks the job as having completed with no result to report.
+func (r *Response) Success() {
+	r.status = StatusSuccess
+}
+
+// SuccessWithData marks the job as complete and records a result later
+// steps (or the caller, via JobHandle.Result) may need.
+func (r *Response) SuccessWithData(data interface{}) {
+	r.status = StatusSuccess
+	r.data = data
+}
+
+// Failure marks the job as failed. err should wrap one of this package's
+// (or a step package's) sentinel errors so failures stay machine-readable.
+func (r *Response) Failure(err error) {
+	r.status = StatusFailure
+	r.err = err
+}
+
+// Job is a single named step of a larger pipeline.
+type Job interface {
+	Name() string
+	Execute(resp *Response)
+}
+
+// Finalizer is implemented by a Job that needs to run cleanup once the
+// whole pipeline it belongs to reaches a terminal state, regardless of
+// which step (if any) failed. Queue.Submit calls Finalize on every step
+// that implements it after the pipeline finishes, success or failure.
+type Finalizer interface {
+	Finalize()
+}
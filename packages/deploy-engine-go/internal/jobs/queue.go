@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrJobPanicked is the sentinel failure recorded on a JobHandle when a
+// step panics instead of returning through Response, so a bad step fails
+// its own pipeline rather than taking down the whole process.
+var ErrJobPanicked = errors.New("ErrJobPanicked")
+
+// handleTTL bounds how long a finished JobHandle is kept around for
+// Queue.Get, so a long-running engine doesn't accumulate one handle (and
+// its buffered logCh/result) per deployment forever.
+const handleTTL = 30 * time.Minute
+
+// LogEvent is a single line emitted as a JobHandle's steps run.
+type LogEvent struct {
+	Job     string
+	Message string
+	Level   string
+}
+
+// JobHandle is returned by Queue.Submit so the caller can poll status,
+// block for completion, or tap a best-effort stream of step events
+// without waiting on the pipeline itself.
+type JobHandle struct {
+	ID string
+
+	mu         sync.Mutex
+	status     Status
+	result     interface{}
+	err        error
+	done       chan struct{}
+	logCh      chan LogEvent
+	finishedAt time.Time
+}
+
+func newJobHandle(id string) *JobHandle {
+	return &JobHandle{
+		ID:     id,
+		status: StatusQueued,
+		done:   make(chan struct{}),
+		logCh:  make(chan LogEvent, 64),
+	}
+}
+
+// Status returns the job's current lifecycle state.
+func (h *JobHandle) Status() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// Result returns the last SuccessWithData payload reported by a step, if
+// any, once the job has finished.
+func (h *JobHandle) Result() interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.result
+}
+
+// Stream returns a channel of step events, closed when the job finishes.
+// It's a best-effort tap, not the log of record - a slow reader drops
+// events rather than blocking the pipeline.
+func (h *JobHandle) Stream() <-chan LogEvent {
+	return h.logCh
+}
+
+// Wait blocks until the job reaches a terminal state or ctx ends,
+// returning the job's final error (nil on success).
+func (h *JobHandle) Wait(ctx context.Context) error {
+	select {
+	case <-h.done:
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *JobHandle) emit(event LogEvent) {
+	select {
+	case h.logCh <- event:
+	default:
+	}
+}
+
+func (h *JobHandle) setRunning() {
+	h.mu.Lock()
+	h.status = StatusRunning
+	h.mu.Unlock()
+}
+
+func (h *JobHandle) recordResult(data interface{}) {
+	h.mu.Lock()
+	h.result = data
+	h.mu.Unlock()
+}
+
+func (h *JobHandle) finish(err error) {
+	h.mu.Lock()
+	h.err = err
+	if err != nil {
+		h.status = StatusFailure
+	} else {
+		h.status = StatusSuccess
+	}
+	h.finishedAt = time.Now()
+	h.mu.Unlock()
+
+	close(h.logCh)
+	close(h.done)
+}
+
+// Queue runs submitted pipelines through a bounded worker pool, serializing
+// steps for the same projectID so two activations never race on the
+// project's "current" symlink.
+type Queue struct {
+	sem chan struct{}
+
+	mu         sync.Mutex
+	projectKey map[string]*sync.Mutex
+	handles    map[string]*JobHandle
+}
+
+// NewQueue creates a Queue that runs at most workers pipelines at once.
+func NewQueue(workers int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Queue{
+		sem:        make(chan struct{}, workers),
+		projectKey: make(map[string]*sync.Mutex),
+		handles:    make(map[string]*JobHandle),
+	}
+}
+
+func (q *Queue) projectLock(projectID string) *sync.Mutex {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	lock, ok := q.projectKey[projectID]
+	if !ok {
+		lock = &sync.Mutex{}
+		q.projectKey[projectID] = lock
+	}
+	return lock
+}
+
+// Submit queues steps to run in order against projectID, serialized
+// against any other pipeline for the same project, and returns a handle
+// immediately so the caller can respond without waiting on the pipeline.
+func (q *Queue) Submit(id, projectID string, steps ...Job) *JobHandle {
+	handle := newJobHandle(id)
+
+	q.mu.Lock()
+	q.sweepHandlesLocked()
+	q.handles[id] = handle
+	q.mu.Unlock()
+
+	go func() {
+		// Acquire the project lock before the global semaphore: queued
+		// pipelines for the same project then block on the lock without
+		// holding a semaphore slot, so they can't starve every other
+		// project's pipelines out of the worker pool.
+		lock := q.projectLock(projectID)
+		lock.Lock()
+		defer lock.Unlock()
+
+		q.sem <- struct{}{}
+		defer func() { <-q.sem }()
+
+		defer finalizeSteps(steps)
+		defer func() {
+			if r := recover(); r != nil {
+				handle.emit(LogEvent{Job: "Queue", Message: fmt.Sprintf("job panicked: %v", r), Level: "error"})
+				handle.finish(fmt.Errorf("%w: %v", ErrJobPanicked, r))
+			}
+		}()
+
+		handle.setRunning()
+
+		for _, step := range steps {
+			resp := &Response{}
+			step.Execute(resp)
+
+			if resp.status == StatusFailure {
+				handle.emit(LogEvent{Job: step.Name(), Message: resp.err.Error(), Level: "error"})
+				handle.finish(resp.err)
+				return
+			}
+
+			handle.emit(LogEvent{Job: step.Name(), Message: "completed", Level: "info"})
+			if resp.data != nil {
+				handle.recordResult(resp.data)
+			}
+		}
+
+		handle.finish(nil)
+	}()
+
+	return handle
+}
+
+// sweepHandlesLocked removes handles that finished more than handleTTL ago.
+// Called with q.mu held, so a long-running engine doesn't accumulate one
+// JobHandle (and its buffered logCh/result) per deployment forever.
+func (q *Queue) sweepHandlesLocked() {
+	now := time.Now()
+	for id, h := range q.handles {
+		h.mu.Lock()
+		expired := !h.finishedAt.IsZero() && now.Sub(h.finishedAt) > handleTTL
+		h.mu.Unlock()
+		if expired {
+			delete(q.handles, id)
+		}
+	}
+}
+
+// finalizeSteps runs Finalize on every step that implements Finalizer, once
+// the pipeline they belong to has reached a terminal state. It runs
+// regardless of which step failed (or whether any did), so a step that
+// registers state at the start of a pipeline (e.g. marking it in-flight for
+// DeployService.Shutdown to drain) can reliably release it at the end.
+func finalizeSteps(steps []Job) {
+	for _, step := range steps {
+		if f, ok := step.(Finalizer); ok {
+			f.Finalize()
+		}
+	}
+}
+
+// Get returns a previously submitted job's handle, if still tracked.
+func (q *Queue) Get(id string) (*JobHandle, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	h, ok := q.handles[id]
+	return h, ok
+}
@@ -2,10 +2,14 @@ package logging
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"os"
+	"sync"
+	"time"
 )
 
 type LogLevel string
@@ -32,28 +36,258 @@ func init() {
 	}
 }
 
-// StreamLog sends a log entry to the control API
-func StreamLog(buildID, message string, level LogLevel) {
-	payload := map[string]string{
-		"logs":  fmt.Sprintf("[Deploy] %s\n", message),
-		"level": string(level),
+// record is one structured log entry, queued for batched shipping to
+// control-api. Seq lets control-api order and dedupe entries that arrive
+// out of order after a retry.
+type record struct {
+	BuildID string   `json:"buildID"`
+	Ts      int64    `json:"ts"`
+	Level   LogLevel `json:"level"`
+	Source  string   `json:"source"`
+	Message string   `json:"message"`
+	Seq     uint64   `json:"seq"`
+}
+
+const (
+	maxBatchSize    = 100              // per-buildID entries shipped in one POST
+	flushInterval   = 500 * time.Millisecond
+	maxQueueSize    = 10000            // bounded retry queue; oldest entries spill when full
+	maxSendAttempts = 5
+)
+
+// shipper buffers log records in memory and flushes them to control-api in
+// batches on a background goroutine, so StreamLog never blocks a deploy
+// step on a per-message HTTP round-trip. Failed batches are retried with
+// exponential backoff and jitter; a batch that still fails after
+// maxSendAttempts is dropped with a log line, not put back on the queue.
+type shipper struct {
+	mu      sync.Mutex
+	queue   []record
+	dropped uint64
+	seqs    map[string]uint64
+
+	client *http.Client
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+var (
+	defaultShipper *shipper
+	shipperOnce    sync.Once
+)
+
+func getShipper() *shipper {
+	shipperOnce.Do(func() {
+		defaultShipper = &shipper{
+			client: &http.Client{Timeout: 10 * time.Second},
+			seqs:   make(map[string]uint64),
+		}
+	})
+	return defaultShipper
+}
+
+// Init starts the background flush loop. Safe to call once at process
+// startup; StreamLog works even before Init runs (entries just queue up),
+// but nothing is actually shipped until it does.
+func Init(ctx context.Context) {
+	s := getShipper()
+
+	s.mu.Lock()
+	if s.done != nil {
+		s.mu.Unlock()
+		return
 	}
+	s.done = make(chan struct{})
+	s.mu.Unlock()
 
-	body, _ := json.Marshal(payload)
-	url := fmt.Sprintf("%s/builds/%s/logs", controlAPIURL, buildID)
+	s.wg.Add(1)
+	go s.run(ctx)
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
-	if err != nil {
-		fmt.Printf("[Logger] Failed to create request: %v\n", err)
+// Shutdown stops the flush loop and makes a best-effort attempt to drain
+// whatever's still queued before ctx expires.
+func Shutdown(ctx context.Context) {
+	s := getShipper()
+
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done == nil {
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	close(done)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+	}
+
+	for s.queueLen() > 0 {
+		s.flush(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// queueLen reports how many records are still waiting to be shipped.
+func (s *shipper) queueLen() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+func (s *shipper) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush(ctx)
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// enqueue appends rec to the queue, spilling the oldest entry when the
+// queue is at capacity so a control-api outage can't grow memory
+// unbounded - bumping the dropped count so Dropped() can surface it.
+func (s *shipper) enqueue(rec record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) >= maxQueueSize {
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	s.queue = append(s.queue, rec)
+}
+
+func (s *shipper) nextSeq(buildID string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seqs[buildID]++
+	return s.seqs[buildID]
+}
+
+// flush ships up to maxBatchSize queued records per buildID as one POST
+// each. A batch that exhausts its retries is dropped with a log line
+// rather than requeued, so one stuck buildID can't starve the others
+// forever.
+func (s *shipper) flush(ctx context.Context) {
+	for buildID, recs := range s.drainBatches() {
+		if err := s.sendWithRetry(ctx, buildID, recs); err != nil {
+			fmt.Printf("[Logger] Giving up on %d log entries for build %s: %v\n", len(recs), buildID, err)
+		}
+	}
+}
+
+// drainBatches removes up to maxBatchSize entries per buildID from the
+// queue and groups them for shipping, leaving any excess queued for the
+// next flush.
+func (s *shipper) drainBatches() map[string][]record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return nil
+	}
+
+	batches := make(map[string][]record)
+	var remaining []record
+	for _, rec := range s.queue {
+		if len(batches[rec.BuildID]) < maxBatchSize {
+			batches[rec.BuildID] = append(batches[rec.BuildID], rec)
+		} else {
+			remaining = append(remaining, rec)
+		}
+	}
+	s.queue = remaining
+	return batches
+}
+
+// sendWithRetry POSTs recs as a single JSON array, retrying with
+// exponential backoff plus jitter on network errors and 5xx responses. A
+// 4xx response is treated as unrecoverable and returned immediately.
+func (s *shipper) sendWithRetry(ctx context.Context, buildID string, recs []record) error {
+	body, err := json.Marshal(recs)
 	if err != nil {
-		fmt.Printf("[Logger] Failed to stream log: %v\n", err)
-		return
+		return fmt.Errorf("failed to marshal batch: %w", err)
 	}
-	defer resp.Body.Close()
+	url := fmt.Sprintf("%s/builds/%s/logs", controlAPIURL, buildID)
+
+	var lastErr error
+	for attempt := 0; attempt < maxSendAttempts; attempt++ {
+		if attempt > 0 {
+			backoff := (1 << uint(attempt-1)) * 200 * time.Millisecond
+			jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+			select {
+			case <-time.After(backoff + jitter):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("control-api returned %d", resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("control-api rejected batch: %d", resp.StatusCode)
+		}
+
+		return nil
+	}
+
+	return lastErr
+}
+
+// Dropped returns how many log entries have been discarded because the
+// queue filled up faster than control-api could accept them.
+func Dropped() uint64 {
+	s := getShipper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// StreamLog enqueues a structured log entry for buildID to be shipped to
+// the control API in the next batch, instead of blocking the caller on a
+// synchronous HTTP round-trip per message.
+func StreamLog(buildID, message string, level LogLevel) {
+	s := getShipper()
+	s.enqueue(record{
+		BuildID: buildID,
+		Ts:      time.Now().UnixMilli(),
+		Level:   level,
+		Source:  "deploy-engine",
+		Message: fmt.Sprintf("[Deploy] %s", message),
+		Seq:     s.nextSeq(buildID),
+	})
 }
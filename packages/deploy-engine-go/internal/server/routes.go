@@ -2,26 +2,58 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/thakurdotdev/deploy-engine/internal/config"
+	"github.com/thakurdotdev/deploy-engine/internal/jobs"
 	"github.com/thakurdotdev/deploy-engine/internal/services"
 	"github.com/thakurdotdev/deploy-engine/internal/utils"
 )
 
+// deployQueueWorkers bounds how many activations run at once; work for a
+// given project is always serialized regardless of this limit.
+const deployQueueWorkers = 4
+
+var deployQueue = jobs.NewQueue(deployQueueWorkers)
+
 func RegisterRoutes(r chi.Router) {
 	deploy := services.GetDeployService()
 
 	r.Post("/ports/check", handlePortCheck)
-	r.Post("/artifacts/upload", handleArtifactUpload(deploy))
-	r.Post("/activate", handleActivate(deploy))
+	r.With(rejectWhileDraining(deploy)).Post("/artifacts/{buildId}/init", handleArtifactInit(deploy))
+	r.With(rejectWhileDraining(deploy)).Patch("/artifacts/{buildId}/{uploadId}", handleArtifactChunk(deploy))
+	r.With(rejectWhileDraining(deploy)).Post("/artifacts/{buildId}/{uploadId}/complete", handleArtifactComplete(deploy))
+	r.Get("/artifacts/{buildId}/{uploadId}", handleArtifactStatus(deploy))
+	r.With(rejectWhileDraining(deploy)).Post("/activate", handleActivate(deploy))
+	r.Get("/jobs/{id}", handleJobStatus)
 	r.Post("/stop", handleStop(deploy))
 	r.Post("/projects/{id}/delete", handleDeleteProject(deploy))
+	r.Post("/projects/{id}/rollback", handleRollback(deploy))
+	r.Get("/deployments/{id}/status", handleDeploymentStatus)
 	r.Get("/*", handleCatchAll)
 }
 
+// rejectWhileDraining gates new uploads/activations out once Shutdown has
+// started draining, so the drain's 30s deadline isn't racing work that
+// walked in after the process already decided to exit. In-flight Deploy
+// calls admitted before draining began are left alone to finish.
+func rejectWhileDraining(deploy *services.DeployService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if deploy.IsDraining() {
+				http.Error(w, "Deploy engine is shutting down", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func handlePortCheck(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Port int `json:"port"`
@@ -39,17 +71,82 @@ func handlePortCheck(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]bool{"available": available})
 }
 
-func handleArtifactUpload(deploy *services.DeployService) http.HandlerFunc {
+// handleArtifactInit starts a chunked artifact upload for buildId and
+// returns the uploadId/chunkSize the caller should use with
+// handleArtifactChunk, replacing the old single-shot upload that had to
+// buffer an entire build tarball in one request.
+func handleArtifactInit(deploy *services.DeployService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buildID := chi.URLParam(r, "buildId")
+
+		uploadID, chunkSize, err := deploy.BeginUpload(buildID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"uploadId":  uploadID,
+			"chunkSize": chunkSize,
+		})
+	}
+}
+
+// handleArtifactChunk appends one chunk of an in-progress upload at the
+// offset given by the "offset" query param (and cross-checked against the
+// Content-Range header if the client sends one). A chunk whose offset
+// doesn't match what's already on disk is rejected with 409 so the client
+// can call handleArtifactStatus and resend from the right place instead of
+// restarting the whole artifact.
+func handleArtifactChunk(deploy *services.DeployService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		buildID := r.URL.Query().Get("buildId")
-		if buildID == "" {
-			http.Error(w, "Missing buildId", http.StatusBadRequest)
+		buildID := chi.URLParam(r, "buildId")
+		uploadID := chi.URLParam(r, "uploadId")
+
+		offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid or missing offset", http.StatusBadRequest)
 			return
 		}
 
-		artifactPath, err := deploy.ReceiveArtifact(buildID, r.Body)
+		if cr := r.Header.Get("Content-Range"); cr != "" {
+			start, ok := parseContentRangeStart(cr)
+			if !ok || start != offset {
+				http.Error(w, "Content-Range does not match offset", http.StatusBadRequest)
+				return
+			}
+		}
+
+		newOffset, err := deploy.AppendChunk(buildID, uploadID, offset, r.Body)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeArtifactUploadError(w, err)
+			return
+		}
+
+		writeJSON(w, map[string]int64{"offset": newOffset})
+	}
+}
+
+// handleArtifactComplete finalizes an upload once the client has sent
+// every chunk, accepting the artifact only if the server-computed sha256
+// matches what's reported.
+func handleArtifactComplete(deploy *services.DeployService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buildID := chi.URLParam(r, "buildId")
+		uploadID := chi.URLParam(r, "uploadId")
+
+		var req struct {
+			Sha256    string `json:"sha256"`
+			TotalSize int64  `json:"totalSize"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		artifactPath, err := deploy.FinalizeUpload(buildID, uploadID, req.Sha256, req.TotalSize)
+		if err != nil {
+			writeArtifactUploadError(w, err)
 			return
 		}
 
@@ -60,6 +157,57 @@ func handleArtifactUpload(deploy *services.DeployService) http.HandlerFunc {
 	}
 }
 
+// handleArtifactStatus reports how many bytes of an upload have been
+// received, so a client that lost its connection mid-upload knows where
+// to resume instead of guessing or restarting.
+func handleArtifactStatus(deploy *services.DeployService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buildID := chi.URLParam(r, "buildId")
+		uploadID := chi.URLParam(r, "uploadId")
+
+		offset, err := deploy.UploadStatus(buildID, uploadID)
+		if err != nil {
+			writeArtifactUploadError(w, err)
+			return
+		}
+
+		writeJSON(w, map[string]int64{"offset": offset})
+	}
+}
+
+// writeArtifactUploadError maps the chunked upload errors to the HTTP
+// status a client should act on: 404 for an unknown/expired upload, 409
+// for an offset or checksum mismatch it can recover from by re-querying
+// status, 500 for anything else.
+func writeArtifactUploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound), errors.Is(err, services.ErrUploadBuildMismatch):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrChunkOffsetMismatch), errors.Is(err, services.ErrChecksumMismatch):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseContentRangeStart extracts the start offset from a "bytes start-
+// end/total" Content-Range header value.
+func parseContentRangeStart(headerValue string) (int64, bool) {
+	spec := strings.TrimPrefix(headerValue, "bytes ")
+	dash := strings.IndexByte(spec, '-')
+	if dash < 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(spec[:dash], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
+// handleActivate queues the activation as a jobs.Queue pipeline and
+// returns 202 Accepted with a job ID immediately, instead of blocking the
+// request for the life of the deployment. Poll /jobs/{id} for the outcome.
 func handleActivate(deploy *services.DeployService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req services.ActivateRequest
@@ -73,15 +221,34 @@ func handleActivate(deploy *services.DeployService) http.HandlerFunc {
 			return
 		}
 
-		if err := deploy.ActivateDeployment(req); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+		handle := deployQueue.Submit(req.BuildID, req.ProjectID, jobs.NewActivationPipeline(deploy, req)...)
 
-		writeJSON(w, map[string]bool{"success": true})
+		w.WriteHeader(http.StatusAccepted)
+		writeJSON(w, map[string]string{"jobId": handle.ID, "status": string(handle.Status())})
 	}
 }
 
+// handleJobStatus reports a queued activation's current status so callers
+// can poll instead of holding the original request open.
+func handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	handle, ok := deployQueue.Get(id)
+	if !ok {
+		http.Error(w, "Unknown job", http.StatusNotFound)
+		return
+	}
+
+	resp := map[string]string{"jobId": handle.ID, "status": string(handle.Status())}
+	if handle.Status() == jobs.StatusFailure {
+		if err := handle.Wait(r.Context()); err != nil {
+			resp["error"] = err.Error()
+		}
+	}
+
+	writeJSON(w, resp)
+}
+
 func handleStop(deploy *services.DeployService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
@@ -129,6 +296,39 @@ func handleDeleteProject(deploy *services.DeployService) http.HandlerFunc {
 	}
 }
 
+func handleRollback(deploy *services.DeployService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		projectID := chi.URLParam(r, "id")
+		if projectID == "" {
+			http.Error(w, "Missing project ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := deploy.Rollback(projectID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, map[string]bool{"success": true})
+	}
+}
+
+// handleDeploymentStatus reports the live container/unhealthy state the
+// Docker event watcher has observed for a project, since a container can
+// die (and get auto-restarted, or give up) long after /activate returned.
+func handleDeploymentStatus(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "id")
+	if projectID == "" {
+		http.Error(w, "Missing project ID", http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, map[string]bool{
+		"running":   services.GetDockerService().IsRunning(projectID),
+		"unhealthy": services.IsDeploymentUnhealthy(projectID),
+	})
+}
+
 func handleCatchAll(w http.ResponseWriter, r *http.Request) {
 	// Placeholder for future static serving or health check
 	w.Header().Set("Content-Type", "text/plain")
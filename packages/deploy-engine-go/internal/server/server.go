@@ -7,12 +7,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/thakurdotdev/deploy-engine/internal/config"
+	"github.com/thakurdotdev/deploy-engine/internal/logging"
+	"github.com/thakurdotdev/deploy-engine/internal/services"
 )
 
 type Server struct {
@@ -64,6 +67,10 @@ func New() *Server {
 func (s *Server) Start() error {
 	cfg := config.Get()
 
+	// Start the batched log shipper so StreamLog calls throughout the
+	// deploy path enqueue instead of blocking on a synchronous HTTP call.
+	logging.Init(context.Background())
+
 	// Initialize Nginx default config in production
 	if config.IsProduction() {
 		go func() {
@@ -90,6 +97,19 @@ func (s *Server) Start() error {
 	return s.http.ListenAndServe()
 }
 
+// StartWithGracefulShutdown starts the server and blocks until it's told to
+// stop, escalating across up to three signals. The first SIGINT/SIGTERM
+// begins draining: rejectWhileDraining starts turning away new
+// uploads/activations while in-flight DockerService.Deploy calls are left to
+// finish against a 30s deadline (see DeployService.Shutdown). A second
+// signal cancels that deadline early, so anything still running is rolled
+// back and stopped immediately instead of waiting out the rest of the
+// drain. A third forces an immediate exit with code 128+signal, the
+// standard shell convention for a signal-terminated process, after a best-
+// effort DeployService.ForceKillAll. SIGQUIT dumps all goroutine stacks to
+// the log when DEBUG is set, which is useful for diagnosing a stuck drain
+// without killing the process; otherwise, in non-production, it's an escape
+// hatch that exits right away.
 func (s *Server) StartWithGracefulShutdown() {
 	// Start server in goroutine
 	go func() {
@@ -99,22 +119,68 @@ func (s *Server) StartWithGracefulShutdown() {
 		}
 	}()
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	signalCount := 0
+	var deployCancel context.CancelFunc
+	for sig := range sigs {
+		if sig == syscall.SIGQUIT {
+			if os.Getenv("DEBUG") != "" {
+				buf := make([]byte, 1<<20)
+				n := runtime.Stack(buf, true)
+				s.logger.Warn("SIGQUIT received, dumping goroutine stacks", "stacks", string(buf[:n]))
+				continue
+			}
+			if !config.IsProduction() {
+				s.logger.Warn("SIGQUIT received, exiting immediately")
+				os.Exit(1)
+			}
+		}
+
+		signalCount++
+		switch {
+		case signalCount == 1:
+			s.logger.Info("Shutting down server, draining in-flight deployments...")
+			var deployCtx context.Context
+			deployCtx, deployCancel = context.WithTimeout(context.Background(), 30*time.Second)
+			go s.drain(deployCtx)
+		case signalCount == 2:
+			s.logger.Warn("Second shutdown signal received, cancelling in-flight deployments")
+			if deployCancel != nil {
+				deployCancel()
+			}
+		default:
+			s.logger.Warn("Third shutdown signal received, forcing exit", "signal", sig)
+			services.GetDeployService().ForceKillAll()
+			os.Exit(128 + int(sig.(syscall.Signal)))
+		}
+	}
+}
 
-	s.logger.Info("Shutting down server...")
+// drain waits out the in-flight deployment drain against deployCtx, then
+// shuts down the HTTP server, exiting the process once both have finished.
+func (s *Server) drain(deployCtx context.Context) {
+	if err := services.GetDeployService().Shutdown(deployCtx); err != nil {
+		s.logger.Warn("Drain ended early, incomplete deployments were rolled back", "error", err)
+	}
 
 	// Give outstanding requests 30s to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer httpCancel()
 
-	if err := s.http.Shutdown(ctx); err != nil {
+	if err := s.http.Shutdown(httpCtx); err != nil {
 		s.logger.Error("Server forced to shutdown", "error", err)
 	}
 
+	// Drain the log shipper last so logs emitted during shutdown itself
+	// still have a chance to reach control-api.
+	logCtx, logCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer logCancel()
+	logging.Shutdown(logCtx)
+
 	s.logger.Info("Server stopped")
+	os.Exit(0)
 }
 
 // NginxInit wraps nginx initialization for server startup
@@ -130,15 +196,16 @@ func (n *NginxInit) CreateDefaultConfig() error {
 	return nil
 }
 
-// recoverDockerLogs recovers log streams for running Docker containers
+// recoverDockerLogs recovers log streams for running Docker containers and
+// re-subscribes the event watcher so they're covered by auto-restart and
+// unhealthy detection after a restart of this process.
 func recoverDockerLogs() {
-	// Delay slightly to ensure Docker daemon is ready
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Printf("[Docker] Failed to recover logs: %v\n", r)
 		}
 	}()
-	
-	// Use the docker package directly to avoid circular deps
+
 	fmt.Println("[Docker] Recovering log streams for running containers...")
+	services.RecoverDockerLogStreams()
 }
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// uploadChunkSize is the chunk size BeginUpload advertises to clients.
+const uploadChunkSize = 8 << 20 // 8 MiB
+
+// uploadTTL bounds how long an upload can sit unfinished before
+// pruneStaleUploads reclaims its state and partial data.
+const uploadTTL = 24 * time.Hour
+
+// Errors returned by the chunked upload methods so handlers can pick the
+// right HTTP status instead of a generic 500.
+var (
+	ErrUploadNotFound      = errors.New("upload not found")
+	ErrUploadBuildMismatch = errors.New("upload does not belong to this build")
+	ErrChunkOffsetMismatch = errors.New("chunk offset does not match bytes already received")
+	ErrChecksumMismatch    = errors.New("artifact checksum does not match")
+)
+
+// uploadState is BeginUpload/AppendChunk/FinalizeUpload's on-disk record
+// for one in-progress chunked upload, persisted as JSON under
+// artifactsDir/.uploads/ so a client can resume after a dropped connection
+// (or this process restarting) by calling UploadStatus for the real offset
+// and resending from there, instead of restarting the whole artifact.
+type uploadState struct {
+	BuildID   string    `json:"buildId"`
+	Offset    int64     `json:"offset"`
+	HashState []byte    `json:"hashState"` // marshaled sha256 digest, so AppendChunk resumes hashing instead of rehashing from the start
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (d *DeployService) uploadsDir() string {
+	return filepath.Join(d.artifactsDir, ".uploads")
+}
+
+func (d *DeployService) uploadStatePath(uploadID string) string {
+	return filepath.Join(d.uploadsDir(), uploadID+".json")
+}
+
+func (d *DeployService) uploadPartPath(uploadID string) string {
+	return filepath.Join(d.uploadsDir(), uploadID+".part")
+}
+
+// BeginUpload starts a new chunked upload for buildID's artifact and
+// returns an uploadID plus the chunk size the caller should use for
+// AppendChunk, replacing the old all-or-nothing ReceiveArtifact with a
+// protocol that can resume a dropped connection instead of restarting a
+// large build tarball from scratch.
+func (d *DeployService) BeginUpload(buildID string) (uploadID string, chunkSize int, err error) {
+	d.pruneStaleUploads()
+
+	if err := os.MkdirAll(d.uploadsDir(), 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create uploads directory: %w", err)
+	}
+
+	uploadID, err = randomUploadID()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to generate upload id: %w", err)
+	}
+
+	part, err := os.Create(d.uploadPartPath(uploadID))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	part.Close()
+
+	state := uploadState{BuildID: buildID, CreatedAt: time.Now()}
+	if err := saveHashState(&state, sha256.New()); err != nil {
+		return "", 0, err
+	}
+	if err := d.writeUploadState(uploadID, state); err != nil {
+		return "", 0, err
+	}
+
+	return uploadID, uploadChunkSize, nil
+}
+
+// AppendChunk writes a chunk starting at offset to uploadID's in-progress
+// upload and returns the new total received. It rejects a chunk whose
+// offset doesn't match what's already on disk with ErrChunkOffsetMismatch,
+// the signal a client uses to recover from a dropped connection: call
+// UploadStatus to learn the real offset and resend from there.
+func (d *DeployService) AppendChunk(buildID, uploadID string, offset int64, chunk io.Reader) (newOffset int64, err error) {
+	state, err := d.readUploadState(uploadID)
+	if err != nil {
+		return 0, err
+	}
+	if state.BuildID != buildID {
+		return 0, ErrUploadBuildMismatch
+	}
+	if offset != state.Offset {
+		return state.Offset, fmt.Errorf("%w: have %d, got chunk at %d", ErrChunkOffsetMismatch, state.Offset, offset)
+	}
+
+	h, err := loadHashState(state)
+	if err != nil {
+		return 0, err
+	}
+
+	part, err := os.OpenFile(d.uploadPartPath(uploadID), os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer part.Close()
+
+	if _, err := part.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek upload file: %w", err)
+	}
+
+	written, err := io.Copy(io.MultiWriter(part, h), chunk)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write chunk: %w", err)
+	}
+
+	state.Offset += written
+	if err := saveHashState(&state, h); err != nil {
+		return 0, err
+	}
+	if err := d.writeUploadState(uploadID, state); err != nil {
+		return 0, err
+	}
+
+	return state.Offset, nil
+}
+
+// FinalizeUpload completes uploadID once the client has sent totalSize
+// bytes, accepting the artifact only if the server's running sha256 over
+// every chunk matches sha256Hex - catching a chunk silently corrupted or
+// reordered in transit - and only then moves the assembled tarball into
+// place for VerifyArtifact/ExtractBuild to pick up.
+func (d *DeployService) FinalizeUpload(buildID, uploadID, sha256Hex string, totalSize int64) (artifactPath string, err error) {
+	state, err := d.readUploadState(uploadID)
+	if err != nil {
+		return "", err
+	}
+	if state.BuildID != buildID {
+		return "", ErrUploadBuildMismatch
+	}
+	if state.Offset != totalSize {
+		return "", fmt.Errorf("%w: received %d of %d bytes", ErrChunkOffsetMismatch, state.Offset, totalSize)
+	}
+
+	h, err := loadHashState(state)
+	if err != nil {
+		return "", err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != sha256Hex {
+		return "", fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, got, sha256Hex)
+	}
+
+	artifactPath = filepath.Join(d.artifactsDir, buildID+".tar.gz")
+	if err := os.Rename(d.uploadPartPath(uploadID), artifactPath); err != nil {
+		return "", fmt.Errorf("failed to finalize artifact: %w", err)
+	}
+	os.Remove(d.uploadStatePath(uploadID))
+
+	return artifactPath, nil
+}
+
+// UploadStatus reports how many bytes of uploadID have been received, so a
+// client can resume an interrupted upload from the right offset instead of
+// guessing or restarting it.
+func (d *DeployService) UploadStatus(buildID, uploadID string) (offset int64, err error) {
+	state, err := d.readUploadState(uploadID)
+	if err != nil {
+		return 0, err
+	}
+	if state.BuildID != buildID {
+		return 0, ErrUploadBuildMismatch
+	}
+	return state.Offset, nil
+}
+
+// pruneStaleUploads removes upload state left behind by a client that
+// never finished (or never will) after uploadTTL, so .uploads/ doesn't
+// accumulate abandoned partial tarballs forever. Swept inline from
+// BeginUpload rather than on a background ticker, the same way
+// gcOldBuilds runs inline after a successful activation.
+func (d *DeployService) pruneStaleUploads() {
+	entries, err := os.ReadDir(d.uploadsDir())
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-uploadTTL)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		uploadID := strings.TrimSuffix(entry.Name(), ".json")
+		state, err := d.readUploadState(uploadID)
+		if err != nil || state.CreatedAt.After(cutoff) {
+			continue
+		}
+
+		os.Remove(d.uploadStatePath(uploadID))
+		os.Remove(d.uploadPartPath(uploadID))
+	}
+}
+
+func (d *DeployService) readUploadState(uploadID string) (uploadState, error) {
+	var state uploadState
+	data, err := os.ReadFile(d.uploadStatePath(uploadID))
+	if err != nil {
+		return state, fmt.Errorf("%w: %s", ErrUploadNotFound, uploadID)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse upload state: %w", err)
+	}
+	return state, nil
+}
+
+func (d *DeployService) writeUploadState(uploadID string, state uploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload state: %w", err)
+	}
+	if err := os.WriteFile(d.uploadStatePath(uploadID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write upload state: %w", err)
+	}
+	return nil
+}
+
+// saveHashState marshals h's running digest into state.HashState so a
+// later AppendChunk call (even in a new process) can resume hashing
+// instead of starting over.
+func saveHashState(state *uploadState, h hash.Hash) error {
+	marshaler, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return fmt.Errorf("hash does not support binary marshaling")
+	}
+	data, err := marshaler.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to marshal hash state: %w", err)
+	}
+	state.HashState = data
+	return nil
+}
+
+// loadHashState restores the sha256 digest state saved by saveHashState.
+func loadHashState(state uploadState) (hash.Hash, error) {
+	h := sha256.New()
+	unmarshaler, ok := h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return nil, fmt.Errorf("hash does not support binary unmarshaling")
+	}
+	if err := unmarshaler.UnmarshalBinary(state.HashState); err != nil {
+		return nil, fmt.Errorf("failed to restore hash state: %w", err)
+	}
+	return h, nil
+}
+
+// randomUploadID generates a URL-safe identifier for a new upload.
+func randomUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDeployService(t *testing.T) *DeployService {
+	t.Helper()
+	return &DeployService{artifactsDir: t.TempDir()}
+}
+
+func TestUpload_FinalizeRoundTrip(t *testing.T) {
+	d := newTestDeployService(t)
+	buildID := "build-1"
+
+	uploadID, chunkSize, err := d.BeginUpload(buildID)
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+	if chunkSize <= 0 {
+		t.Fatalf("BeginUpload() chunkSize = %d, want > 0", chunkSize)
+	}
+
+	part1 := []byte("hello, ")
+	part2 := []byte("world")
+
+	offset, err := d.AppendChunk(buildID, uploadID, 0, bytes.NewReader(part1))
+	if err != nil {
+		t.Fatalf("AppendChunk(0) error = %v", err)
+	}
+	if offset != int64(len(part1)) {
+		t.Fatalf("AppendChunk(0) offset = %d, want %d", offset, len(part1))
+	}
+
+	offset, err = d.AppendChunk(buildID, uploadID, offset, bytes.NewReader(part2))
+	if err != nil {
+		t.Fatalf("AppendChunk(%d) error = %v", len(part1), err)
+	}
+	total := int64(len(part1) + len(part2))
+	if offset != total {
+		t.Fatalf("AppendChunk() offset = %d, want %d", offset, total)
+	}
+
+	sum := sha256.Sum256(append(append([]byte{}, part1...), part2...))
+	sha256Hex := hex.EncodeToString(sum[:])
+
+	artifactPath, err := d.FinalizeUpload(buildID, uploadID, sha256Hex, total)
+	if err != nil {
+		t.Fatalf("FinalizeUpload() error = %v", err)
+	}
+
+	data, err := os.ReadFile(artifactPath)
+	if err != nil {
+		t.Fatalf("ReadFile(%s): %v", artifactPath, err)
+	}
+	if string(data) != "hello, world" {
+		t.Fatalf("finalized artifact content = %q", data)
+	}
+	if artifactPath != filepath.Join(d.artifactsDir, buildID+".tar.gz") {
+		t.Fatalf("artifactPath = %q, want under artifactsDir", artifactPath)
+	}
+
+	if _, err := os.Stat(d.uploadStatePath(uploadID)); !os.IsNotExist(err) {
+		t.Fatalf("upload state still exists after FinalizeUpload: %v", err)
+	}
+}
+
+func TestAppendChunk_RejectsOffsetMismatch(t *testing.T) {
+	d := newTestDeployService(t)
+	buildID := "build-1"
+
+	uploadID, _, err := d.BeginUpload(buildID)
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	if _, err := d.AppendChunk(buildID, uploadID, 5, bytes.NewReader([]byte("data"))); !errors.Is(err, ErrChunkOffsetMismatch) {
+		t.Fatalf("AppendChunk() error = %v, want ErrChunkOffsetMismatch", err)
+	}
+}
+
+func TestAppendChunk_RejectsWrongBuild(t *testing.T) {
+	d := newTestDeployService(t)
+
+	uploadID, _, err := d.BeginUpload("build-1")
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	if _, err := d.AppendChunk("build-2", uploadID, 0, bytes.NewReader([]byte("data"))); !errors.Is(err, ErrUploadBuildMismatch) {
+		t.Fatalf("AppendChunk() error = %v, want ErrUploadBuildMismatch", err)
+	}
+}
+
+func TestAppendChunk_UnknownUpload(t *testing.T) {
+	d := newTestDeployService(t)
+
+	if _, err := d.AppendChunk("build-1", "no-such-upload", 0, bytes.NewReader([]byte("data"))); !errors.Is(err, ErrUploadNotFound) {
+		t.Fatalf("AppendChunk() error = %v, want ErrUploadNotFound", err)
+	}
+}
+
+func TestFinalizeUpload_RejectsChecksumMismatch(t *testing.T) {
+	d := newTestDeployService(t)
+	buildID := "build-1"
+
+	uploadID, _, err := d.BeginUpload(buildID)
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	chunk := []byte("payload")
+	offset, err := d.AppendChunk(buildID, uploadID, 0, bytes.NewReader(chunk))
+	if err != nil {
+		t.Fatalf("AppendChunk() error = %v", err)
+	}
+
+	if _, err := d.FinalizeUpload(buildID, uploadID, "not-the-real-hash", offset); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("FinalizeUpload() error = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestFinalizeUpload_RejectsIncompleteUpload(t *testing.T) {
+	d := newTestDeployService(t)
+	buildID := "build-1"
+
+	uploadID, _, err := d.BeginUpload(buildID)
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	chunk := []byte("payload")
+	offset, err := d.AppendChunk(buildID, uploadID, 0, bytes.NewReader(chunk))
+	if err != nil {
+		t.Fatalf("AppendChunk() error = %v", err)
+	}
+
+	if _, err := d.FinalizeUpload(buildID, uploadID, "irrelevant", offset+10); !errors.Is(err, ErrChunkOffsetMismatch) {
+		t.Fatalf("FinalizeUpload() error = %v, want ErrChunkOffsetMismatch", err)
+	}
+}
+
+// TestUpload_ResumeAfterDroppedConnection simulates a client that loses its
+// connection mid-upload: it calls UploadStatus to learn the real offset
+// instead of trusting what it last sent, then resumes from there, instead of
+// restarting the whole artifact.
+func TestUpload_ResumeAfterDroppedConnection(t *testing.T) {
+	d := newTestDeployService(t)
+	buildID := "build-1"
+
+	uploadID, _, err := d.BeginUpload(buildID)
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	part1 := []byte("first chunk ")
+	if _, err := d.AppendChunk(buildID, uploadID, 0, bytes.NewReader(part1)); err != nil {
+		t.Fatalf("AppendChunk(0) error = %v", err)
+	}
+
+	// Connection drops here; the client reconnects and asks where to resume.
+	resumeFrom, err := d.UploadStatus(buildID, uploadID)
+	if err != nil {
+		t.Fatalf("UploadStatus() error = %v", err)
+	}
+	if resumeFrom != int64(len(part1)) {
+		t.Fatalf("UploadStatus() = %d, want %d", resumeFrom, len(part1))
+	}
+
+	part2 := []byte("second chunk")
+	offset, err := d.AppendChunk(buildID, uploadID, resumeFrom, bytes.NewReader(part2))
+	if err != nil {
+		t.Fatalf("AppendChunk(resume) error = %v", err)
+	}
+
+	full := append(append([]byte{}, part1...), part2...)
+	if offset != int64(len(full)) {
+		t.Fatalf("AppendChunk(resume) offset = %d, want %d", offset, len(full))
+	}
+
+	sum := sha256.Sum256(full)
+	artifactPath, err := d.FinalizeUpload(buildID, uploadID, hex.EncodeToString(sum[:]), offset)
+	if err != nil {
+		t.Fatalf("FinalizeUpload() error = %v", err)
+	}
+
+	data, err := os.ReadFile(artifactPath)
+	if err != nil {
+		t.Fatalf("ReadFile(%s): %v", artifactPath, err)
+	}
+	if string(data) != string(full) {
+		t.Fatalf("finalized artifact content = %q, want %q", data, full)
+	}
+}
+
+// TestUpload_ResumeAcrossNewHashState exercises the hash-state persistence
+// AppendChunk relies on to resume hashing instead of rehashing from byte
+// zero: reload state from disk (as a new process restarting mid-upload
+// would) before writing the final chunk.
+func TestUpload_ResumeAcrossNewHashState(t *testing.T) {
+	d := newTestDeployService(t)
+	buildID := "build-1"
+
+	uploadID, _, err := d.BeginUpload(buildID)
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	part1 := []byte("persisted-across-restart ")
+	offset, err := d.AppendChunk(buildID, uploadID, 0, bytes.NewReader(part1))
+	if err != nil {
+		t.Fatalf("AppendChunk(0) error = %v", err)
+	}
+
+	state, err := d.readUploadState(uploadID)
+	if err != nil {
+		t.Fatalf("readUploadState() error = %v", err)
+	}
+	if _, err := loadHashState(state); err != nil {
+		t.Fatalf("loadHashState() error = %v", err)
+	}
+
+	part2 := []byte("after-restart")
+	offset, err = d.AppendChunk(buildID, uploadID, offset, bytes.NewReader(part2))
+	if err != nil {
+		t.Fatalf("AppendChunk(resume) error = %v", err)
+	}
+
+	full := append(append([]byte{}, part1...), part2...)
+	sum := sha256.Sum256(full)
+	if _, err := d.FinalizeUpload(buildID, uploadID, hex.EncodeToString(sum[:]), offset); err != nil {
+		t.Fatalf("FinalizeUpload() error = %v", err)
+	}
+}
+
+func TestPruneStaleUploads_RemovesOnlyExpiredUploads(t *testing.T) {
+	d := newTestDeployService(t)
+
+	freshID, _, err := d.BeginUpload("build-fresh")
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+
+	staleID, _, err := d.BeginUpload("build-stale")
+	if err != nil {
+		t.Fatalf("BeginUpload() error = %v", err)
+	}
+	state, err := d.readUploadState(staleID)
+	if err != nil {
+		t.Fatalf("readUploadState() error = %v", err)
+	}
+	state.CreatedAt = time.Now().Add(-2 * uploadTTL)
+	if err := d.writeUploadState(staleID, state); err != nil {
+		t.Fatalf("writeUploadState() error = %v", err)
+	}
+
+	d.pruneStaleUploads()
+
+	if _, err := d.readUploadState(freshID); err != nil {
+		t.Fatalf("fresh upload was pruned: %v", err)
+	}
+	if _, err := d.readUploadState(staleID); !errors.Is(err, ErrUploadNotFound) {
+		t.Fatalf("stale upload survived prune: err = %v", err)
+	}
+	if _, err := os.Stat(d.uploadPartPath(staleID)); !os.IsNotExist(err) {
+		t.Fatalf("stale upload's partial data survived prune: %v", err)
+	}
+}
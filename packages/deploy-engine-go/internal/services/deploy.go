@@ -1,25 +1,70 @@
 package services
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/thakurdotdev/deploy-engine/internal/config"
+	"github.com/thakurdotdev/deploy-engine/internal/services/docker"
 )
 
+// Errors returned by extractArtifact so callers can StreamLog specifics
+// instead of a generic "extraction failed".
+var (
+	ErrArtifactTooLarge = errors.New("artifact exceeds maximum allowed size")
+	ErrUnsafePath       = errors.New("artifact entry escapes extraction target")
+)
+
+// maxArtifactSize bounds how much an artifact can expand to, guarding
+// against decompression-bomb style tarballs.
+const maxArtifactSize = 2 << 30 // 2 GiB
+
+// buildGenerationsToKeep bounds how many build directories gcOldBuilds
+// retains behind "current", mirroring PruneProjectImages' default.
+const buildGenerationsToKeep = 3
+
+// ErrShuttingDown is returned by BeginActivation once Shutdown has been
+// called, so callers get a clear, machine-checkable reason instead of a
+// deployment that silently races the process exit.
+var ErrShuttingDown = errors.New("deploy engine is shutting down")
+
+// Deployment is an opaque handle to a single in-flight activation pipeline,
+// returned by BeginActivation and passed back to EndActivation, so Shutdown
+// can drain it cleanly: roll the "current" symlink back to whatever it
+// replaced, stop whatever it started, and free the slot.
+type Deployment struct {
+	projectID  string
+	buildID    string
+	port       int
+	projectDir string
+	docker     bool
+	done       chan struct{}
+}
+
 type DeployService struct {
 	artifactsDir string
 	appsDir      string
 	nginx        *NginxService
+
+	mu           sync.Mutex
+	inflight     map[string]*Deployment
+	wg           sync.WaitGroup
+	shuttingDown bool
 }
 
 func NewDeployService() *DeployService {
@@ -48,6 +93,7 @@ func NewDeployService() *DeployService {
 		artifactsDir: artifactsDir,
 		appsDir:      appsDir,
 		nginx:        NewNginxService(),
+		inflight:     make(map[string]*Deployment),
 	}
 }
 
@@ -58,136 +104,171 @@ type ActivateRequest struct {
 	AppType   config.AppType    `json:"appType"`
 	Subdomain string            `json:"subdomain"`
 	EnvVars   map[string]string `json:"envVars"`
+
+	// Strategy is docker.StrategyRecreate (default, the zero value) or
+	// docker.StrategyBlueGreen. Blue-green only takes effect in Docker
+	// mode with an existing live container to run alongside; anything
+	// else falls back to recreate.
+	Strategy string `json:"strategy"`
 }
 
-func (d *DeployService) ReceiveArtifact(buildID string, body io.Reader) (string, error) {
+// --- Granular steps ---
+//
+// The jobs package composes these into a queued, per-project-serialized
+// pipeline (see jobs.NewActivationPipeline) instead of one long blocking
+// call, so a step can be retried and reported on independently and the
+// HTTP layer gets a job ID back immediately. BeginActivation/EndActivation
+// bracket the whole pipeline so Shutdown still sees it as one in-flight
+// deployment, the same as when this was a single synchronous method.
+
+// VerifyArtifact checks that buildID's previously uploaded artifact is on
+// disk, so a pipeline fails fast with a clear error instead of a generic
+// "no such file" surfacing deep inside tar extraction.
+func (d *DeployService) VerifyArtifact(buildID string) (string, error) {
 	artifactPath := filepath.Join(d.artifactsDir, buildID+".tar.gz")
+	if _, err := os.Stat(artifactPath); err != nil {
+		return "", fmt.Errorf("artifact not found for build %s: %w", buildID, err)
+	}
+	return artifactPath, nil
+}
 
-	file, err := os.Create(artifactPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create artifact file: %w", err)
+// ExtractBuild extracts req's artifact into its build directory and
+// records how it was activated, so a later rollback can restart it.
+func (d *DeployService) ExtractBuild(req ActivateRequest) (string, error) {
+	paths := d.getPaths(req.ProjectID, req.BuildID)
+
+	if err := d.extractArtifact(paths.artifact, paths.buildDir); err != nil {
+		return "", err
 	}
-	defer file.Close()
 
-	if _, err := io.Copy(file, body); err != nil {
-		return "", fmt.Errorf("failed to write artifact: %w", err)
+	if err := d.writeBuildMeta(paths.buildDir, req); err != nil {
+		StreamLog(req.BuildID, fmt.Sprintf("Failed to persist build metadata: %v", err), LogLevelWarning)
 	}
 
-	return artifactPath, nil
+	return paths.buildDir, nil
 }
 
-func (d *DeployService) ActivateDeployment(req ActivateRequest) error {
+// ActivateBuild points "current" at buildDir and resolves the real
+// directory behind the symlink for the steps that follow.
+func (d *DeployService) ActivateBuild(req ActivateRequest, buildDir string) (string, error) {
 	paths := d.getPaths(req.ProjectID, req.BuildID)
 
-	StreamLog(req.BuildID, "Starting deployment activation...", LogLevelInfo)
-
-	// 1. Extract artifact
-	StreamLog(req.BuildID, "Extracting artifact...", LogLevelInfo)
-	if err := d.extractArtifact(paths.artifact, paths.buildDir); err != nil {
-		StreamLog(req.BuildID, fmt.Sprintf("Failed to extract artifact: %v", err), LogLevelError)
-		return err
+	if err := d.updateSymlink(paths.projectDir, buildDir, req.BuildID); err != nil {
+		return "", err
 	}
 
-	// 2. Update symlink for zero-downtime
-	StreamLog(req.BuildID, "Updating deployment symlink...", LogLevelInfo)
-	if err := d.updateSymlink(paths.projectDir, paths.buildDir, req.BuildID); err != nil {
-		StreamLog(req.BuildID, fmt.Sprintf("Failed to update symlink: %v", err), LogLevelError)
-		return err
-	}
+	d.gcOldBuilds(paths.projectDir, buildGenerationsToKeep)
 
-	currentLink := filepath.Join(paths.projectDir, "current")
-	currentDir, err := filepath.EvalSymlinks(currentLink)
-	if err != nil {
-		StreamLog(req.BuildID, fmt.Sprintf("Failed to resolve symlink: %v", err), LogLevelError)
-		return err
+	return filepath.EvalSymlinks(filepath.Join(paths.projectDir, "current"))
+}
+
+// InstallDeps installs dependencies for a process-mode build. It's a no-op
+// for Docker mode (the image build installs its own) and static builds.
+func (d *DeployService) InstallDeps(req ActivateRequest, currentDir string) error {
+	if config.Get().UseDocker || config.ShouldUseStaticServer(req.AppType, currentDir) {
+		return nil
 	}
 
-	// Check if Docker mode is enabled
-	if config.Get().UseDocker {
-		return d.activateWithDocker(req, currentDir)
+	fw := config.Frameworks[req.AppType]
+	if !fw.RequiresInstall {
+		return nil
 	}
 
-	return d.activateWithProcess(req, currentDir, paths.projectDir)
+	return d.ensureDependenciesInstalled(currentDir)
 }
 
-// activateWithDocker deploys using Docker containers
-func (d *DeployService) activateWithDocker(req ActivateRequest, sourceDir string) error {
-	StreamLog(req.BuildID, "Using Docker deployment mode...", LogLevelInfo)
+// StartApp starts currentDir as a container in Docker mode, or as a
+// managed process otherwise. It's a no-op for static builds, which nginx
+// serves directly.
+func (d *DeployService) StartApp(req ActivateRequest, currentDir string) error {
+	paths := d.getPaths(req.ProjectID, req.BuildID)
 
-	dockerSvc := GetDockerService()
-	success, _, err := dockerSvc.Deploy(
-		req.ProjectID, req.BuildID, sourceDir,
-		req.Port, string(req.AppType), req.EnvVars,
-	)
+	if config.Get().UseDocker {
+		success, _, err := GetDockerService().Deploy(
+			req.ProjectID, req.BuildID, currentDir,
+			req.Port, string(req.AppType), req.EnvVars, req.Strategy,
+		)
+		if err != nil {
+			return err
+		}
+		if !success {
+			return fmt.Errorf("container failed to start")
+		}
+		return nil
+	}
 
-	if err != nil || !success {
-		return err
+	if config.ShouldUseStaticServer(req.AppType, currentDir) {
+		return nil
 	}
 
-	// Configure Nginx
-	if config.IsProduction() && req.Subdomain != "" {
-		StreamLog(req.BuildID, "Configuring Nginx...", LogLevelInfo)
-		if err := d.nginx.CreateConfig(req.Subdomain, req.Port); err != nil {
-			StreamLog(req.BuildID, fmt.Sprintf("Failed to configure Nginx: %v", err), LogLevelWarning)
-		}
+	d.killProjectProcess(req.ProjectID, req.Port)
+	if err := d.ensurePortFree(req.Port); err != nil {
+		return err
 	}
 
-	return nil
+	return d.startApplication(currentDir, req.Port, req.AppType, paths.projectDir, req.BuildID, req.EnvVars)
 }
 
-// activateWithProcess deploys by running the app directly (original flow)
-func (d *DeployService) activateWithProcess(req ActivateRequest, currentDir, projectDir string) error {
-	// Check if static server needed
-	if config.ShouldUseStaticServer(req.AppType, currentDir) {
-		StreamLog(req.BuildID, "Static build detected, using static server...", LogLevelInfo)
-	} else {
-		// Kill existing process
-		StreamLog(req.BuildID, "Stopping existing process...", LogLevelInfo)
-		d.killProjectProcess(req.ProjectID, req.Port)
+// HealthCheck re-runs the readiness probe for req's port. Docker mode
+// already health-gates inside StartApp, so this is a no-op there.
+func (d *DeployService) HealthCheck(req ActivateRequest) error {
+	if config.Get().UseDocker {
+		return nil
+	}
+	return d.performHealthCheck(req.Port)
+}
 
-		// Ensure port is free
-		if err := d.ensurePortFree(req.Port); err != nil {
-			StreamLog(req.BuildID, fmt.Sprintf("Port %d not available: %v", req.Port, err), LogLevelError)
-			return err
-		}
+// ConfigureNginx wires req's subdomain to its port in production. For a
+// blue-green Docker deploy with an existing config to cut over from, it
+// switches the upstream instead of rewriting the server block in place,
+// so traffic only moves once the new container is confirmed healthy.
+func (d *DeployService) ConfigureNginx(req ActivateRequest) error {
+	if !config.IsProduction() || req.Subdomain == "" {
+		return nil
+	}
 
-		// Install dependencies if needed
-		fw := config.Frameworks[req.AppType]
-		if fw.RequiresInstall {
-			StreamLog(req.BuildID, "Installing dependencies...", LogLevelInfo)
-			if err := d.ensureDependenciesInstalled(currentDir); err != nil {
-				StreamLog(req.BuildID, fmt.Sprintf("Failed to install dependencies: %v", err), LogLevelError)
-				return err
-			}
-		}
+	if req.Strategy == docker.StrategyBlueGreen && config.Get().UseDocker && d.nginx.ConfigExists(req.Subdomain) {
+		return d.switchTrafficBlueGreen(req)
+	}
 
-		// Start application
-		StreamLog(req.BuildID, "Starting application...", LogLevelInfo)
-		if err := d.startApplication(currentDir, req.Port, req.AppType, projectDir, req.BuildID, req.EnvVars); err != nil {
-			StreamLog(req.BuildID, fmt.Sprintf("Failed to start application: %v", err), LogLevelError)
-			return err
-		}
+	return d.nginx.CreateConfig(req.Subdomain, req.Port)
+}
 
-		// Health check
-		StreamLog(req.BuildID, "Performing health check...", LogLevelInfo)
-		if err := d.performHealthCheck(req.Port); err != nil {
-			StreamLog(req.BuildID, fmt.Sprintf("Health check failed: %v", err), LogLevelError)
-			return err
-		}
+// switchTrafficBlueGreen flips req.Subdomain's nginx upstream to the
+// freshly started candidate container's port - re-confirming it's
+// healthy first - and only then stops the container it replaces.
+func (d *DeployService) switchTrafficBlueGreen(req ActivateRequest) error {
+	if _, err := d.nginx.SwitchUpstream(req.Subdomain, req.Port); err != nil {
+		return fmt.Errorf("failed to switch traffic: %w", err)
 	}
 
-	// Configure Nginx
-	if config.IsProduction() && req.Subdomain != "" {
-		StreamLog(req.BuildID, "Configuring Nginx...", LogLevelInfo)
-		if err := d.nginx.CreateConfig(req.Subdomain, req.Port); err != nil {
-			StreamLog(req.BuildID, fmt.Sprintf("Failed to configure Nginx: %v", err), LogLevelWarning)
-		}
+	if !GetDockerService().PromoteCandidate(req.ProjectID, req.BuildID) {
+		StreamLog(req.BuildID, "Traffic switched to the new container, but the old one could not be stopped", LogLevelWarning)
 	}
 
-	StreamLog(req.BuildID, "Deployment activated successfully!", LogLevelSuccess)
 	return nil
 }
 
+// RecoverFailedStart is called when StartApp or HealthCheck fails: it
+// stops whatever the failed attempt started and rolls "current" back to
+// the previous build, mirroring the pre-pipeline activation's own failure
+// path.
+func (d *DeployService) RecoverFailedStart(req ActivateRequest) error {
+	if config.Get().UseDocker {
+		// A failed blue-green candidate already tears itself down inside
+		// Deploy, leaving the live container untouched - stopping "the"
+		// container here would take down the still-good one instead.
+		if req.Strategy != docker.StrategyBlueGreen {
+			GetDockerService().Stop(req.ProjectID, req.BuildID)
+		}
+	} else {
+		d.killProjectProcess(req.ProjectID, req.Port)
+	}
+
+	paths := d.getPaths(req.ProjectID, req.BuildID)
+	return d.autoRollback(req.ProjectID, req.BuildID, paths.projectDir)
+}
+
 func (d *DeployService) StopDeployment(port int, projectID, buildID string) error {
 	if buildID != "" {
 		StreamLog(buildID, "Stopping deployment...", LogLevelInfo)
@@ -231,6 +312,131 @@ func (d *DeployService) DeleteProject(projectID string, port int, subdomain stri
 	return nil
 }
 
+// IsDraining reports whether Shutdown has been called, so HTTP handlers can
+// reject new work at the edge instead of letting it reach BeginActivation
+// only to be turned away with ErrShuttingDown.
+func (d *DeployService) IsDraining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.shuttingDown
+}
+
+// BeginActivation registers req as in-flight, rejecting it outright if the
+// engine is already draining for shutdown.
+func (d *DeployService) BeginActivation(req ActivateRequest) (*Deployment, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.shuttingDown {
+		return nil, ErrShuttingDown
+	}
+
+	dep := &Deployment{
+		projectID:  req.ProjectID,
+		buildID:    req.BuildID,
+		port:       req.Port,
+		projectDir: filepath.Join(d.appsDir, req.ProjectID),
+		docker:     config.Get().UseDocker,
+		done:       make(chan struct{}),
+	}
+
+	d.wg.Add(1)
+	d.inflight[dep.buildID] = dep
+	return dep, nil
+}
+
+// EndActivation marks dep as having reached a terminal state (success or
+// failure) and releases its shutdown-drain slot.
+func (d *DeployService) EndActivation(dep *Deployment) {
+	close(dep.done)
+
+	d.mu.Lock()
+	delete(d.inflight, dep.buildID)
+	d.mu.Unlock()
+
+	d.wg.Done()
+}
+
+// Shutdown blocks new activations and waits for every in-flight one to
+// reach a terminal state (health-check pass, failure, or ctx's deadline).
+// Anything still running when ctx expires is rolled back to the build
+// "current" pointed at before it started and force-stopped, so a restart
+// mid-activation never leaves an orphan process, a half-extracted build, or
+// "current" pointing at a dead app.
+func (d *DeployService) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.shuttingDown = true
+	remaining := make([]*Deployment, 0, len(d.inflight))
+	for _, dep := range d.inflight {
+		remaining = append(remaining, dep)
+	}
+	d.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+
+	for _, dep := range remaining {
+		d.rollbackIncomplete(dep)
+	}
+	return ctx.Err()
+}
+
+// rollbackIncomplete is Shutdown's per-deployment cleanup for anything
+// still running when the drain deadline passes: roll "current" back to the
+// build it was replacing when one is recorded, or just log the failure
+// when there's nothing to roll back to, then stop whatever it started.
+func (d *DeployService) rollbackIncomplete(dep *Deployment) {
+	select {
+	case <-dep.done:
+		return // reached a terminal state while Shutdown was iterating
+	default:
+	}
+
+	previousLink := filepath.Join(dep.projectDir, "current.previous")
+	if prevTarget, err := os.Readlink(previousLink); err == nil {
+		if rbErr := d.updateSymlink(dep.projectDir, prevTarget, dep.buildID); rbErr == nil {
+			StreamLog(dep.buildID, fmt.Sprintf("Shutdown deadline reached, rolled back to %s", prevTarget), LogLevelWarning)
+		}
+	} else {
+		StreamLog(dep.buildID, "Shutdown deadline reached with no previous build to roll back to", LogLevelError)
+	}
+
+	if dep.docker {
+		docker.EnsureContainerStopped(dep.projectID)
+	} else {
+		d.killProjectProcess(dep.projectID, dep.port)
+	}
+}
+
+// ForceKillAll is the last-resort path for a third shutdown signal: it
+// skips the rollback/health-check dance Shutdown does and just stops
+// everything still tracked as in-flight, in place, immediately.
+func (d *DeployService) ForceKillAll() {
+	d.mu.Lock()
+	deps := make([]*Deployment, 0, len(d.inflight))
+	for _, dep := range d.inflight {
+		deps = append(deps, dep)
+	}
+	d.mu.Unlock()
+
+	for _, dep := range deps {
+		if dep.docker {
+			docker.EnsureContainerStopped(dep.projectID)
+		} else {
+			d.killProjectProcess(dep.projectID, dep.port)
+		}
+	}
+}
+
 // --- Helper Methods ---
 
 type deployPaths struct {
@@ -247,21 +453,147 @@ func (d *DeployService) getPaths(projectID, buildID string) deployPaths {
 	}
 }
 
+// extractArtifact extracts a gzip'd tarball into target using the
+// standard library instead of shelling out to the tar binary. Every
+// entry's target path is validated to stay within target (closing off
+// zip-slip via crafted "../" or absolute paths), symlinks whose target
+// would escape are rejected, and the total expanded size is capped to
+// guard against decompression bombs.
 func (d *DeployService) extractArtifact(artifact, target string) error {
-	os.MkdirAll(target, 0755)
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return fmt.Errorf("failed to create extraction target: %w", err)
+	}
 
-	cmd := exec.Command("tar", "-xzf", artifact, "-C", target)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("tar extraction failed: %s", string(out))
+	file, err := os.Open(artifact)
+	if err != nil {
+		return fmt.Errorf("failed to open artifact: %w", err)
 	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	var extracted int64
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		entryPath, err := safeJoin(target, header.Name)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(entryPath, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", header.Name, err)
+			}
+
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(entryPath), 0755); err != nil {
+				return fmt.Errorf("failed to create directory for %s: %w", header.Name, err)
+			}
+
+			out, err := os.OpenFile(entryPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, regularFileMode(header.Mode))
+			if err != nil {
+				return fmt.Errorf("failed to create file %s: %w", header.Name, err)
+			}
+
+			written, err := io.Copy(out, io.LimitReader(tr, maxArtifactSize-extracted+1))
+			out.Close()
+			if err != nil {
+				return fmt.Errorf("failed to write file %s: %w", header.Name, err)
+			}
+
+			extracted += written
+			if extracted > maxArtifactSize {
+				return ErrArtifactTooLarge
+			}
+
+		case tar.TypeSymlink:
+			// Resolve Linkname the same way the kernel would - relative to
+			// the symlink's own directory - and reject it if that lands
+			// outside target, the same as any other entry. The original
+			// (still relative) Linkname is what actually gets written, so
+			// a build directory that's later moved or copied keeps working.
+			linkDir, err := filepath.Rel(target, filepath.Dir(entryPath))
+			if err != nil || filepath.IsAbs(header.Linkname) {
+				return fmt.Errorf("%w: symlink %s -> %s", ErrUnsafePath, header.Name, header.Linkname)
+			}
+			if _, err := safeJoin(target, filepath.Join(linkDir, header.Linkname)); err != nil {
+				return fmt.Errorf("%w: symlink %s -> %s", ErrUnsafePath, header.Name, header.Linkname)
+			}
+
+			os.Remove(entryPath)
+			if err := os.Symlink(header.Linkname, entryPath); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", header.Name, err)
+			}
+
+		default:
+			// Ignore device files, fifos, etc. - not valid in a deploy artifact.
+		}
+	}
+
 	return nil
 }
 
+// safeJoin resolves name against base and rejects it outright if it's
+// absolute or contains a "../" that would land outside base, instead of
+// silently clamping a traversal attempt to some other path inside base -
+// a crafted artifact entry should fail extraction, not get silently
+// remapped to an attacker-chosen filename in the tree.
+func safeJoin(base, name string) (string, error) {
+	cleaned := filepath.Clean(name)
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", ErrUnsafePath
+	}
+
+	full := filepath.Join(base, cleaned)
+	rel, err := filepath.Rel(base, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrUnsafePath
+	}
+	return full, nil
+}
+
+// regularFileMode masks a tar entry's mode down to 0755/0644, preserving
+// only the executable bit from the original permissions.
+func regularFileMode(mode int64) os.FileMode {
+	if mode&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
 func (d *DeployService) updateSymlink(projectDir, target, buildID string) error {
 	os.MkdirAll(projectDir, 0755)
 
 	currentLink := filepath.Join(projectDir, "current")
+	previousLink := filepath.Join(projectDir, "current.previous")
 	tempLink := filepath.Join(projectDir, "current.tmp")
+	tempPreviousLink := filepath.Join(projectDir, "current.previous.tmp")
+
+	// Record the outgoing target before we replace it, via the same
+	// temp-symlink-rename trick used for "current" itself, so a reader
+	// never observes current.previous half-written or momentarily
+	// missing. A failed activation (or a shutdown mid-drain) rolls back
+	// to whatever this captures.
+	if prevTarget, err := os.Readlink(currentLink); err == nil {
+		os.Remove(tempPreviousLink)
+		if err := os.Symlink(prevTarget, tempPreviousLink); err == nil {
+			os.Rename(tempPreviousLink, previousLink)
+		}
+	}
 
 	// Remove old temp link if exists
 	os.Remove(tempLink)
@@ -279,6 +611,160 @@ func (d *DeployService) updateSymlink(projectDir, target, buildID string) error
 	return nil
 }
 
+// buildMeta is what a build directory remembers about how it was
+// activated, persisted as env.json so a rollback can restart it without
+// the caller re-supplying the original request.
+type buildMeta struct {
+	Port      int               `json:"port"`
+	AppType   config.AppType    `json:"appType"`
+	Subdomain string            `json:"subdomain"`
+	EnvVars   map[string]string `json:"envVars"`
+}
+
+func (d *DeployService) writeBuildMeta(buildDir string, req ActivateRequest) error {
+	meta := buildMeta{Port: req.Port, AppType: req.AppType, Subdomain: req.Subdomain, EnvVars: req.EnvVars}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal build metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(buildDir, "env.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write build metadata: %w", err)
+	}
+	return nil
+}
+
+func (d *DeployService) readBuildMeta(buildDir string) (buildMeta, error) {
+	var meta buildMeta
+	data, err := os.ReadFile(filepath.Join(buildDir, "env.json"))
+	if err != nil {
+		return meta, fmt.Errorf("failed to read build metadata: %w", err)
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("failed to parse build metadata: %w", err)
+	}
+	return meta, nil
+}
+
+// autoRollback is the automatic counterpart invoked when a freshly
+// activated build fails its health check: it resolves current.previous
+// and, if one is recorded, rolls back to it.
+func (d *DeployService) autoRollback(projectID, triggerBuildID, projectDir string) error {
+	previousLink := filepath.Join(projectDir, "current.previous")
+	prevTarget, err := os.Readlink(previousLink)
+	if err != nil {
+		return fmt.Errorf("no previous build recorded: %w", err)
+	}
+	return d.rollbackTo(projectID, prevTarget, triggerBuildID)
+}
+
+// Rollback is the operator-initiated counterpart to autoRollback, exposed
+// so a bad deployment can be reverted without waiting for a new one to be
+// pushed.
+func (d *DeployService) Rollback(projectID string) error {
+	projectDir := filepath.Join(d.appsDir, projectID)
+	previousLink := filepath.Join(projectDir, "current.previous")
+
+	prevTarget, err := os.Readlink(previousLink)
+	if err != nil {
+		return fmt.Errorf("no previous build recorded for project %s: %w", projectID, err)
+	}
+
+	return d.rollbackTo(projectID, prevTarget, filepath.Base(prevTarget))
+}
+
+// rollbackTo swaps "current" back to prevBuildDir and restarts it from its
+// own stored env.json, re-running its health check so operators never see
+// a "successful" rollback that's actually still down. logBuildID is the
+// build whose StreamLog the outcome is reported against - the build that
+// triggered the rollback, or prevBuildID itself for a manual Rollback.
+func (d *DeployService) rollbackTo(projectID, prevBuildDir, logBuildID string) error {
+	projectDir := filepath.Join(d.appsDir, projectID)
+	prevBuildID := filepath.Base(prevBuildDir)
+
+	meta, err := d.readBuildMeta(prevBuildDir)
+	if err != nil {
+		return fmt.Errorf("no stored metadata for previous build %s: %w", prevBuildID, err)
+	}
+
+	if err := d.updateSymlink(projectDir, prevBuildDir, prevBuildID); err != nil {
+		return fmt.Errorf("failed to roll back symlink: %w", err)
+	}
+
+	if config.Get().UseDocker {
+		// A blue-green failure never stopped the previous build's
+		// container, so it's already serving - redeploying it here would
+		// just be a needless restart.
+		if !GetDockerService().IsRunning(projectID) {
+			success, _, err := GetDockerService().Deploy(
+				projectID, prevBuildID, prevBuildDir,
+				meta.Port, string(meta.AppType), meta.EnvVars, docker.StrategyRecreate,
+			)
+			if err != nil || !success {
+				return fmt.Errorf("failed to restart previous build %s: %w", prevBuildID, err)
+			}
+		}
+	} else if !config.ShouldUseStaticServer(meta.AppType, prevBuildDir) {
+		d.killProjectProcess(projectID, meta.Port)
+		if err := d.startApplication(prevBuildDir, meta.Port, meta.AppType, projectDir, prevBuildID, meta.EnvVars); err != nil {
+			return fmt.Errorf("failed to restart previous build %s: %w", prevBuildID, err)
+		}
+		if err := d.performHealthCheck(meta.Port); err != nil {
+			return fmt.Errorf("previous build %s failed health check too: %w", prevBuildID, err)
+		}
+	}
+
+	if config.IsProduction() && meta.Subdomain != "" {
+		d.nginx.CreateConfig(meta.Subdomain, meta.Port)
+	}
+
+	StreamLog(logBuildID, fmt.Sprintf("Rolled back to %s", prevBuildID), LogLevelWarning)
+
+	d.gcOldBuilds(projectDir, buildGenerationsToKeep)
+
+	return nil
+}
+
+// gcOldBuilds removes build directories beyond buildGenerationsToKeep
+// generations behind "current", bounding the disk space extracted builds
+// consume over the life of a project. The build "current" and
+// "current.previous" point at are always kept regardless of age.
+func (d *DeployService) gcOldBuilds(projectDir string, keep int) {
+	buildsDir := filepath.Join(projectDir, "builds")
+	entries, err := os.ReadDir(buildsDir)
+	if err != nil {
+		return
+	}
+
+	currentTarget, _ := os.Readlink(filepath.Join(projectDir, "current"))
+	previousTarget, _ := os.Readlink(filepath.Join(projectDir, "current.previous"))
+
+	type candidate struct {
+		path    string
+		modTime time.Time
+	}
+	var candidates []candidate
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(buildsDir, entry.Name())
+		if path == currentTarget || path == previousTarget {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{path: path, modTime: info.ModTime()})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].modTime.After(candidates[j].modTime) })
+
+	for i := keep; i < len(candidates); i++ {
+		os.RemoveAll(candidates[i].path)
+	}
+}
+
 func (d *DeployService) killProjectProcess(projectID string, port int) {
 	if port <= 0 {
 		return
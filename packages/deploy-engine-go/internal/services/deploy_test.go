@@ -0,0 +1,188 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tarEntry describes one entry to bake into a test tarball.
+type tarEntry struct {
+	name     string // tar header Name
+	linkname string // tar header Linkname, for TypeSymlink
+	typeflag byte
+	body     string
+}
+
+// buildArtifact gzips a tarball containing entries and writes it to path.
+func buildArtifact(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, e := range entries {
+		typeflag := e.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		hdr := &tar.Header{
+			Name:     e.name,
+			Linkname: e.linkname,
+			Typeflag: typeflag,
+			Mode:     0644,
+			Size:     int64(len(e.body)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader(%s): %v", e.name, err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatalf("Write(%s): %v", e.name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar Close: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip Close: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	base := "/var/apps/project/builds/build1"
+
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{name: "plain file", entry: "index.js", wantErr: false},
+		{name: "nested file", entry: "src/index.js", wantErr: false},
+		{name: "dot-clean nested file", entry: "./src/index.js", wantErr: false},
+		{name: "traversal", entry: "../../../../etc/passwd", wantErr: true},
+		{name: "traversal inside nested path", entry: "foo/../../../bar", wantErr: true},
+		{name: "absolute path", entry: "/etc/passwd", wantErr: true},
+		{name: "bare parent", entry: "..", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := safeJoin(base, tt.entry)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsafePath) {
+					t.Fatalf("safeJoin(%q) = (%q, %v), want ErrUnsafePath", tt.entry, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("safeJoin(%q) returned unexpected error: %v", tt.entry, err)
+			}
+			rel, relErr := filepath.Rel(base, got)
+			if relErr != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				t.Fatalf("safeJoin(%q) = %q escapes base %q", tt.entry, got, base)
+			}
+		})
+	}
+}
+
+func TestExtractArtifact_RegularFiles(t *testing.T) {
+	d := &DeployService{}
+	dir := t.TempDir()
+	artifact := filepath.Join(dir, "build.tar.gz")
+	target := filepath.Join(dir, "extracted")
+
+	buildArtifact(t, artifact, []tarEntry{
+		{name: "package.json", body: `{"name":"app"}`},
+		{name: "src/index.js", body: "console.log('hi')"},
+	})
+
+	if err := d.extractArtifact(artifact, target); err != nil {
+		t.Fatalf("extractArtifact() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(target, "src/index.js"))
+	if err != nil {
+		t.Fatalf("expected extracted file, got: %v", err)
+	}
+	if string(data) != "console.log('hi')" {
+		t.Fatalf("extracted file content = %q", data)
+	}
+}
+
+func TestExtractArtifact_RejectsTraversal(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry tarEntry
+	}{
+		{
+			name:  "relative traversal in regular file name",
+			entry: tarEntry{name: "../../../../etc/passwd", body: "pwned"},
+		},
+		{
+			name:  "absolute regular file name",
+			entry: tarEntry{name: "/etc/passwd", body: "pwned"},
+		},
+		{
+			name:  "symlink escaping via relative traversal",
+			entry: tarEntry{name: "evil-link", typeflag: tar.TypeSymlink, linkname: "../../../../etc/passwd"},
+		},
+		{
+			name:  "symlink escaping via absolute target",
+			entry: tarEntry{name: "evil-link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &DeployService{}
+			dir := t.TempDir()
+			artifact := filepath.Join(dir, "build.tar.gz")
+			target := filepath.Join(dir, "extracted")
+
+			buildArtifact(t, artifact, []tarEntry{tt.entry})
+
+			err := d.extractArtifact(artifact, target)
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Fatalf("extractArtifact() error = %v, want ErrUnsafePath", err)
+			}
+
+			if _, statErr := os.Lstat(filepath.Join(filepath.Dir(target), "etc", "passwd")); statErr == nil {
+				t.Fatal("extractArtifact() escaped target and wrote outside it")
+			}
+		})
+	}
+}
+
+func TestExtractArtifact_AllowsSafeSymlink(t *testing.T) {
+	d := &DeployService{}
+	dir := t.TempDir()
+	artifact := filepath.Join(dir, "build.tar.gz")
+	target := filepath.Join(dir, "extracted")
+
+	buildArtifact(t, artifact, []tarEntry{
+		{name: "bin/real-tool", body: "#!/bin/sh\necho hi"},
+		{name: "bin/tool", typeflag: tar.TypeSymlink, linkname: "real-tool"},
+	})
+
+	if err := d.extractArtifact(artifact, target); err != nil {
+		t.Fatalf("extractArtifact() error = %v", err)
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(target, "bin/tool"))
+	if err != nil {
+		t.Fatalf("EvalSymlinks: %v", err)
+	}
+	if resolved != filepath.Join(target, "bin/real-tool") {
+		t.Fatalf("symlink resolved to %q, want %q", resolved, filepath.Join(target, "bin/real-tool"))
+	}
+}
@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxCacheHistory is how many previous successful images we keep per
+// project to feed back in as --cache-from sources.
+const maxCacheHistory = 2
+
+// buildCachePath stores the per-project cache-from history as a JSON file
+// under the OS temp dir, so it survives engine restarts.
+var buildCachePath = filepath.Join(os.TempDir(), "thakur-deploy-build-cache.json")
+
+type buildCacheStore struct {
+	mu   sync.Mutex
+	data map[string][]string // projectID -> image refs, newest first
+}
+
+var (
+	cacheStore     *buildCacheStore
+	cacheStoreOnce sync.Once
+)
+
+func getBuildCache() *buildCacheStore {
+	cacheStoreOnce.Do(func() {
+		cacheStore = &buildCacheStore{data: map[string][]string{}}
+		cacheStore.load()
+	})
+	return cacheStore
+}
+
+func (s *buildCacheStore) load() {
+	raw, err := os.ReadFile(buildCachePath)
+	if err != nil {
+		return
+	}
+	var data map[string][]string
+	if json.Unmarshal(raw, &data) == nil {
+		s.data = data
+	}
+}
+
+func (s *buildCacheStore) save() {
+	raw, err := json.Marshal(s.data)
+	if err != nil {
+		return
+	}
+	os.WriteFile(buildCachePath, raw, 0644)
+}
+
+// CacheFrom returns the images to pass as --cache-from for a project's next
+// build, newest first.
+func (s *buildCacheStore) CacheFrom(projectID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.data[projectID]...)
+}
+
+// RecordSuccess remembers imageName as a cache source for the project's
+// next build, trimming the history to maxCacheHistory entries.
+func (s *buildCacheStore) RecordSuccess(projectID, imageName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := append([]string{imageName}, s.data[projectID]...)
+	if len(history) > maxCacheHistory {
+		history = history[:maxCacheHistory]
+	}
+	s.data[projectID] = history
+	s.save()
+}
@@ -0,0 +1,436 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Client wraps the Docker Engine API client, replacing the old
+// exec.Command("docker", ...) shell-outs with direct daemon calls.
+type Client struct {
+	api *client.Client
+}
+
+// NewClient opens a connection to the local Docker daemon, honoring the
+// usual DOCKER_HOST / DOCKER_API_VERSION environment variables.
+func NewClient() (*Client, error) {
+	api, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return &Client{api: api}, nil
+}
+
+// NewClientWithHost opens a connection to the Docker Engine API at host
+// (e.g. "tcp://127.0.0.1:12345"), skipping version negotiation against a
+// real daemon. This is what dockertest.Server hands to SetClient so tests
+// run against its fake instead of dialing a real daemon.
+func NewClientWithHost(host, apiVersion string) (*Client, error) {
+	api, err := client.NewClientWithOpts(client.WithHost(host), client.WithVersion(apiVersion))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return &Client{api: api}, nil
+}
+
+var (
+	defaultClient     *Client
+	defaultClientErr  error
+	defaultClientOnce sync.Once
+
+	overrideMu sync.Mutex
+	override   *Client
+)
+
+// getClient returns the process-wide Docker API client, creating it on
+// first use, unless a test has pointed it at a fake server with SetClient.
+func getClient() (*Client, error) {
+	overrideMu.Lock()
+	c := override
+	overrideMu.Unlock()
+	if c != nil {
+		return c, nil
+	}
+
+	defaultClientOnce.Do(func() {
+		defaultClient, defaultClientErr = NewClient()
+	})
+	return defaultClient, defaultClientErr
+}
+
+// SetClient points every free function in this package (RunContainer,
+// BuildImage, StopAndRemoveContainer, ...) at c instead of the real Docker
+// daemon, so tests can exercise them against dockertest's fake server.
+// Passing nil restores the default, lazily-initialized daemon client.
+func SetClient(c *Client) {
+	overrideMu.Lock()
+	defer overrideMu.Unlock()
+	override = c
+}
+
+// Ping checks that the daemon is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.api.Ping(ctx)
+	return err
+}
+
+// BuildImage builds an image from sourceDir, streaming the JSON progress
+// messages through onLog, and returns the built image's tag. cacheFrom is
+// a list of previously built images (newest first) used to warm the
+// BuildKit layer cache for incremental rebuilds.
+func (c *Client) BuildImage(ctx context.Context, sourceDir, imageName string, cacheFrom []string, onLog func(string)) error {
+	buildCtx, err := archive.TarWithOptions(sourceDir, &archive.TarOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create build context: %w", err)
+	}
+	defer buildCtx.Close()
+
+	resp, err := c.api.ImageBuild(ctx, buildCtx, dockertypes.ImageBuildOptions{
+		Tags:       []string{imageName},
+		Dockerfile: "Dockerfile",
+		Remove:     true,
+		Version:    dockertypes.BuilderBuildKit,
+		CacheFrom:  cacheFrom,
+		// BUILDKIT_INLINE_CACHE embeds cache metadata in the pushed/tagged
+		// image itself, equivalent to buildx's `--cache-to type=inline`,
+		// so the next build (here or on a sibling node) can use it via
+		// CacheFrom without a separate cache export.
+		BuildArgs: map[string]*string{
+			"BUILDKIT_INLINE_CACHE": strPtr("1"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("image build request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return streamJSONMessages(resp.Body, onLog)
+}
+
+func strPtr(s string) *string { return &s }
+
+// applySecurity translates a SecurityConfig into the docker API's
+// capability, security-opt, tmpfs, and user settings.
+func applySecurity(containerCfg *container.Config, hostCfg *container.HostConfig, sec *SecurityConfig) {
+	if sec == nil {
+		return
+	}
+
+	containerCfg.User = sec.User
+
+	hostCfg.CapDrop = sec.CapDrop
+	hostCfg.CapAdd = sec.CapAdd
+	hostCfg.ReadonlyRootfs = sec.ReadOnlyRootfs
+	hostCfg.PidsLimit = &sec.PidsLimit
+
+	if len(sec.Tmpfs) > 0 {
+		hostCfg.Tmpfs = sec.Tmpfs
+	}
+
+	var securityOpt []string
+	if sec.NoNewPrivileges {
+		securityOpt = append(securityOpt, "no-new-privileges")
+	}
+	if sec.MACProfile != "" {
+		securityOpt = append(securityOpt, sec.MACProfile)
+	}
+	hostCfg.SecurityOpt = securityOpt
+}
+
+// RunContainer creates and starts a container from cfg, returning its ID.
+func (c *Client) RunContainer(ctx context.Context, cfg ContainerConfig) (string, error) {
+	env := make([]string, 0, len(cfg.EnvVars)+1)
+	for k, v := range cfg.EnvVars {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	if _, hasPort := cfg.EnvVars["PORT"]; !hasPort {
+		env = append(env, fmt.Sprintf("PORT=%d", cfg.InternalPort))
+	}
+	env = append(env, "NODE_ENV=production")
+
+	containerCfg := &container.Config{
+		Image: cfg.ImageName,
+		Env:   env,
+		Labels: map[string]string{
+			"thakur.projectId": cfg.ProjectID,
+			"thakur.buildId":   cfg.BuildID,
+		},
+		ExposedPorts: natPortSet(cfg.InternalPort),
+		Healthcheck:  toHealthConfig(cfg.HealthCheck),
+	}
+
+	hostCfg := &container.HostConfig{
+		PortBindings:  natPortMap(cfg.HostPort, cfg.InternalPort),
+		RestartPolicy: parseRestartPolicy(cfg.RestartPolicy),
+		Resources: container.Resources{
+			Memory:   parseMemoryLimit(cfg.MemoryLimit),
+			NanoCPUs: parseCPULimit(cfg.CPULimit),
+		},
+	}
+	if cfg.StopGracePeriod > 0 {
+		timeout := int(cfg.StopGracePeriod.Seconds())
+		containerCfg.StopTimeout = &timeout
+	}
+	applySecurity(containerCfg, hostCfg, cfg.Security)
+
+	created, err := c.api.ContainerCreate(ctx, containerCfg, hostCfg, nil, nil, cfg.ContainerName)
+	if err != nil {
+		return "", fmt.Errorf("container create failed: %w", err)
+	}
+
+	if err := c.api.ContainerStart(ctx, created.ID, dockertypes.ContainerStartOptions{}); err != nil {
+		return "", fmt.Errorf("container start failed: %w", err)
+	}
+
+	return created.ID, nil
+}
+
+// WaitForHealthy polls the daemon's reported health status for a container
+// until it becomes "healthy", the container has no healthcheck configured
+// and is running (in which case it's considered ready), it reports
+// "unhealthy", or timeout elapses.
+func (c *Client) WaitForHealthy(ctx context.Context, containerID string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		info, err := c.api.ContainerInspect(ctx, containerID)
+		if err != nil {
+			return fmt.Errorf("inspect failed: %w", err)
+		}
+
+		if info.State.Health == nil {
+			// No HEALTHCHECK configured; fall back to "running" as ready.
+			if info.State.Running {
+				return nil
+			}
+		} else {
+			switch info.State.Health.Status {
+			case dockertypes.Healthy:
+				return nil
+			case dockertypes.Unhealthy:
+				return fmt.Errorf("container reported unhealthy")
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for container to become healthy")
+		}
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// parseRestartPolicy converts the "no|on-failure:N|always|unless-stopped"
+// strings used throughout config into the docker API's restart policy.
+func parseRestartPolicy(policy string) container.RestartPolicy {
+	if policy == "" {
+		policy = DefaultRestartPolicy
+	}
+
+	name, maxRetry, _ := strings.Cut(policy, ":")
+	retries := 0
+	if maxRetry != "" {
+		retries, _ = strconv.Atoi(maxRetry)
+	}
+
+	return container.RestartPolicy{
+		Name:              name,
+		MaximumRetryCount: retries,
+	}
+}
+
+// toHealthConfig converts our HealthCheckConfig into the docker API's
+// container.HealthConfig, returning nil when no healthcheck is configured
+// (meaning the image's own HEALTHCHECK, if any, applies).
+func toHealthConfig(hc *HealthCheckConfig) *container.HealthConfig {
+	if hc == nil || len(hc.Test) == 0 {
+		return nil
+	}
+	return &container.HealthConfig{
+		Test:        hc.Test,
+		Interval:    hc.Interval,
+		Timeout:     hc.Timeout,
+		Retries:     hc.Retries,
+		StartPeriod: hc.StartPeriod,
+	}
+}
+
+// RemoveImage force-removes an image by name.
+func (c *Client) RemoveImage(ctx context.Context, imageName string) error {
+	_, err := c.api.ImageRemove(ctx, imageName, dockertypes.ImageRemoveOptions{Force: true})
+	return err
+}
+
+// ListProjectImages returns images whose repository matches the given
+// project prefix, newest first.
+func (c *Client) ListProjectImages(ctx context.Context, repoPrefix string) ([]dockertypes.ImageSummary, error) {
+	f := filters.NewArgs(filters.Arg("reference", repoPrefix+":*"))
+	images, err := c.api.ImageList(ctx, dockertypes.ImageListOptions{Filters: f})
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// StopContainer gracefully stops a container, giving it up to timeout
+// seconds to shut down before the daemon sends SIGKILL.
+func (c *Client) StopContainer(ctx context.Context, containerName string, timeout int) error {
+	return c.api.ContainerStop(ctx, containerName, container.StopOptions{Timeout: &timeout})
+}
+
+// RemoveContainer removes a container, optionally force-killing it first.
+func (c *Client) RemoveContainer(ctx context.Context, containerName string, force bool) error {
+	return c.api.ContainerRemove(ctx, containerName, dockertypes.ContainerRemoveOptions{Force: force})
+}
+
+// RenameContainer renames a container.
+func (c *Client) RenameContainer(ctx context.Context, oldName, newName string) error {
+	return c.api.ContainerRename(ctx, oldName, newName)
+}
+
+// StartContainer (re)starts an existing, stopped container.
+func (c *Client) StartContainer(ctx context.Context, containerName string) error {
+	return c.api.ContainerStart(ctx, containerName, dockertypes.ContainerStartOptions{})
+}
+
+// SubscribeEvents opens a long-lived connection to the Docker Events API,
+// filtered to container events for containers this system manages (those
+// carrying a "thakur.projectId" label), and returns the message/error
+// channels the caller ranges over until ctx is cancelled.
+func (c *Client) SubscribeEvents(ctx context.Context) (<-chan events.Message, <-chan error) {
+	f := filters.NewArgs(
+		filters.Arg("type", string(events.ContainerEventType)),
+		filters.Arg("label", "thakur.projectId"),
+	)
+	return c.api.Events(ctx, dockertypes.EventsOptions{Filters: f})
+}
+
+// InspectContainer returns the container's current state, or nil if it
+// doesn't exist.
+func (c *Client) InspectContainer(ctx context.Context, containerName string) *ContainerInfo {
+	info, err := c.api.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil
+	}
+	return &ContainerInfo{ID: info.ID, Name: containerName, Status: info.State.Status}
+}
+
+// ContainerLogs returns up to tail lines of a container's combined
+// stdout/stderr, demultiplexing the daemon's framed log stream.
+func (c *Client) ContainerLogs(ctx context.Context, containerName string, tail int) (string, error) {
+	reader, err := c.api.ContainerLogs(ctx, containerName, dockertypes.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(tail),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var out, errOut bytes.Buffer
+	if _, err := stdcopy.StdCopy(&out, &errOut, reader); err != nil && err != io.EOF {
+		return "", err
+	}
+	return out.String() + errOut.String(), nil
+}
+
+// StreamLogs streams a container's combined stdout/stderr to onLog as it
+// is produced, starting from the tail of the existing log. It returns a
+// CancelFunc that stops the stream by cancelling the underlying request,
+// rather than killing a `docker logs` subprocess.
+func (c *Client) StreamLogs(containerName string, onLog func(string)) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		reader, err := c.api.ContainerLogs(ctx, containerName, dockertypes.ContainerLogsOptions{
+			ShowStdout: true,
+			ShowStderr: true,
+			Follow:     true,
+			Tail:       "0",
+		})
+		if err != nil {
+			return
+		}
+		defer reader.Close()
+
+		pr, pw := io.Pipe()
+		go func() {
+			_, _ = stdcopy.StdCopy(pw, pw, reader)
+			pw.Close()
+		}()
+
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			onLog(scanner.Text())
+		}
+	}()
+
+	return cancel
+}
+
+// ListContainers returns containers managed by this system, identified by
+// the "thakur.projectId" label.
+func (c *Client) ListContainers(ctx context.Context) ([]RunningContainer, error) {
+	f := filters.NewArgs(filters.Arg("label", "thakur.projectId"))
+	list, err := c.api.ContainerList(ctx, dockertypes.ContainerListOptions{Filters: f})
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]RunningContainer, 0, len(list))
+	for _, item := range list {
+		name := strings.TrimPrefix(firstOrEmpty(item.Names), "/")
+		containers = append(containers, RunningContainer{
+			ContainerName: name,
+			ProjectID:     item.Labels["thakur.projectId"],
+			BuildID:       item.Labels["thakur.buildId"],
+		})
+	}
+	return containers, nil
+}
+
+func firstOrEmpty(ss []string) string {
+	if len(ss) == 0 {
+		return ""
+	}
+	return ss[0]
+}
+
+func streamJSONMessages(r io.Reader, onLog func(string)) error {
+	return jsonmessage.DisplayJSONMessagesStream(r, logWriter{onLog}, 0, false, nil)
+}
+
+// logWriter adapts an onLog callback to io.Writer so it can be handed to
+// jsonmessage.DisplayJSONMessagesStream.
+type logWriter struct {
+	onLog func(string)
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	if w.onLog != nil {
+		w.onLog(string(p))
+	}
+	return len(p), nil
+}
+
+// contextWithTimeout is a small helper so callers don't need to import
+// "time" and "context" just to bound a Docker API call.
+func contextWithTimeout(d time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), d)
+}
@@ -1,70 +1,62 @@
 package docker
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os/exec"
-	"strings"
+	"time"
 )
 
-// RunContainer starts a container with the given configuration
-func RunContainer(config ContainerConfig) RunResult {
-	// Build environment variable flags
-	envFlags := []string{}
-	for key, value := range config.EnvVars {
-		envFlags = append(envFlags, "-e", fmt.Sprintf("%s=%s", key, value))
-	}
+// healthGateTimeout bounds how long RunContainer waits for the daemon to
+// report a freshly started container healthy before giving up.
+const healthGateTimeout = 60 * time.Second
 
-	// Ensure PORT is always set
-	if _, hasPort := config.EnvVars["PORT"]; !hasPort {
-		envFlags = append(envFlags, "-e", fmt.Sprintf("PORT=%d", config.InternalPort))
+// RunContainer starts a container with the given configuration and, if a
+// HealthCheck is configured, blocks until the daemon reports it healthy so
+// callers (like the nginx upstream swap) only see a container confirmed to
+// be serving requests.
+func RunContainer(config ContainerConfig) RunResult {
+	cli, err := getClient()
+	if err != nil {
+		return RunResult{Success: false, Error: err.Error()}
 	}
 
-	args := []string{
-		"run", "-d",
-		"--name", config.ContainerName,
-		"-p", fmt.Sprintf("%d:%d", config.HostPort, config.InternalPort),
-		"--restart", "unless-stopped",
-		"--memory", config.MemoryLimit,
-		"--cpus", config.CPULimit,
-		"--label", fmt.Sprintf("thakur.projectId=%s", config.ProjectID),
-		"--label", fmt.Sprintf("thakur.buildId=%s", config.BuildID),
-		"-e", "NODE_ENV=production",
+	ctx := context.Background()
+	containerID, err := cli.RunContainer(ctx, config)
+	if err != nil {
+		return RunResult{Success: false, Error: err.Error()}
 	}
-	args = append(args, envFlags...)
-	args = append(args, config.ImageName)
-
-	result := ExecDocker(args...)
 
-	if result.ExitCode != 0 {
-		errMsg := result.Stderr
-		if errMsg == "" {
-			errMsg = "Failed to start container"
+	if config.HealthCheck != nil {
+		if err := cli.WaitForHealthy(ctx, containerID, healthGateTimeout); err != nil {
+			return RunResult{Success: false, ContainerID: containerID, Error: fmt.Sprintf("container did not become healthy: %v", err)}
 		}
-		return RunResult{Success: false, Error: errMsg}
 	}
 
 	return RunResult{
 		Success:     true,
-		ContainerID: strings.TrimSpace(result.Stdout),
+		ContainerID: containerID,
 	}
 }
 
-// StopContainer gracefully stops a container
+// StopContainer gracefully stops a container. The stop is registered with
+// the EventWatcher first so the "die" event it produces is recognized as
+// intentional rather than treated as a crash to auto-restart.
 func StopContainer(containerName string, timeout int) bool {
-	result := ExecDocker("stop", "-t", fmt.Sprintf("%d", timeout), containerName)
-	return result.ExitCode == 0
+	cli, err := getClient()
+	if err != nil {
+		return false
+	}
+	GetEventWatcher().ExpectStop(containerName)
+	return cli.StopContainer(context.Background(), containerName, timeout) == nil
 }
 
 // RemoveContainer removes a container
 func RemoveContainer(containerName string, force bool) bool {
-	args := []string{"rm"}
-	if force {
-		args = append(args, "-f")
+	cli, err := getClient()
+	if err != nil {
+		return false
 	}
-	args = append(args, containerName)
-	result := ExecDocker(args...)
-	return result.ExitCode == 0
+	return cli.RemoveContainer(context.Background(), containerName, force) == nil
 }
 
 // StopAndRemoveContainer stops then removes a container
@@ -75,27 +67,16 @@ func StopAndRemoveContainer(containerName string) bool {
 
 // GetContainerInfo returns container state information
 func GetContainerInfo(containerName string) *ContainerInfo {
-	result := ExecDocker("inspect", "--format", "{{.Id}} {{.State.Status}}", containerName)
-	if result.ExitCode != 0 {
+	cli, err := getClient()
+	if err != nil {
 		return nil
 	}
-
-	parts := strings.Fields(strings.TrimSpace(result.Stdout))
-	if len(parts) < 2 {
-		return nil
-	}
-
-	return &ContainerInfo{
-		ID:     parts[0],
-		Name:   containerName,
-		Status: parts[1],
-	}
+	return cli.InspectContainer(context.Background(), containerName)
 }
 
 // ContainerExists checks if a container exists
 func ContainerExists(containerName string) bool {
-	result := ExecDocker("container", "inspect", containerName)
-	return result.ExitCode == 0
+	return GetContainerInfo(containerName) != nil
 }
 
 // IsContainerRunning checks if a container is currently running
@@ -106,36 +87,38 @@ func IsContainerRunning(containerName string) bool {
 
 // GetContainerLogs returns the last N lines of container logs
 func GetContainerLogs(containerName string, tail int) string {
-	result := ExecDocker("logs", "--tail", fmt.Sprintf("%d", tail), containerName)
-	return result.Stdout + result.Stderr
+	cli, err := getClient()
+	if err != nil {
+		return ""
+	}
+	logs, err := cli.ContainerLogs(context.Background(), containerName, tail)
+	if err != nil {
+		return ""
+	}
+	return logs
 }
 
-// StreamContainerLogs streams container logs in real-time
-// Returns a cancel function to stop streaming
+// StreamContainerLogs streams container logs in real-time.
+// Returns a cancel function to stop streaming by cancelling the
+// underlying API request rather than killing a subprocess.
 func StreamContainerLogs(containerName string, onLog func(string)) func() {
-	cmd := exec.Command("docker", "logs", "-f", "--tail", "0", containerName)
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
-
-	cmd.Start()
-
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			onLog(scanner.Text())
-		}
-	}()
-
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			onLog(scanner.Text())
-		}
-	}()
+	cli, err := getClient()
+	if err != nil {
+		return func() {}
+	}
+	cancel := cli.StreamLogs(containerName, onLog)
+	return func() { cancel() }
+}
 
-	return func() {
-		cmd.Process.Kill()
+// RenameContainer renames a container, used to promote a blue-green
+// candidate to its project's stable name once traffic has been switched
+// over to it.
+func RenameContainer(oldName, newName string) bool {
+	cli, err := getClient()
+	if err != nil {
+		return false
 	}
+	return cli.RenameContainer(context.Background(), oldName, newName) == nil
 }
 
 // EnsureContainerStopped stops and removes any existing container for a project
@@ -155,30 +138,13 @@ type RunningContainer struct {
 
 // ListRunningContainers returns all containers managed by this system
 func ListRunningContainers() []RunningContainer {
-	result := ExecDocker(
-		"ps", "--format", "{{.Names}} {{.Label \"thakur.projectId\"}} {{.Label \"thakur.buildId\"}}",
-		"--filter", "label=thakur.projectId",
-	)
-
-	if result.ExitCode != 0 {
+	cli, err := getClient()
+	if err != nil {
 		return nil
 	}
-
-	var containers []RunningContainer
-	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.Fields(line)
-		if len(parts) >= 3 {
-			containers = append(containers, RunningContainer{
-				ContainerName: parts[0],
-				ProjectID:     parts[1],
-				BuildID:       parts[2],
-			})
-		}
+	containers, err := cli.ListContainers(context.Background())
+	if err != nil {
+		return nil
 	}
-
 	return containers
 }
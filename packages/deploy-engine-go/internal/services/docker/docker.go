@@ -1,11 +1,13 @@
 package docker
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/thakurdotdev/deploy-engine/internal/config"
 	"github.com/thakurdotdev/deploy-engine/internal/logging"
 )
 
@@ -19,15 +21,35 @@ func NewDockerService() *DockerService {
 	return &DockerService{}
 }
 
-// Deploy deploys an application as a Docker container
+// Deploy deploys an application as a Docker container using strategy
+// ("recreate" or "blue-green", defaulting to "recreate" for any other
+// value). Blue-green only applies when a container for projectID is
+// already running - a first deploy has nothing to run alongside, so it
+// always recreates.
 func (d *DockerService) Deploy(
 	projectID, buildID, sourceDir string,
 	hostPort int,
 	appType string,
 	envVars map[string]string,
+	strategy string,
+) (success bool, containerID string, err error) {
+	if strategy == StrategyBlueGreen && IsContainerRunning(GetContainerName(projectID)) {
+		return d.deployBlueGreen(projectID, buildID, sourceDir, hostPort, appType, envVars)
+	}
+	return d.deployRecreate(projectID, buildID, sourceDir, hostPort, appType, envVars)
+}
+
+// deployRecreate is the original deployment flow: stop whatever is
+// currently running for the project, then build and start the new
+// container in its place.
+func (d *DockerService) deployRecreate(
+	projectID, buildID, sourceDir string,
+	hostPort int,
+	appType string,
+	envVars map[string]string,
 ) (success bool, containerID string, err error) {
 	containerName := GetContainerName(projectID)
-	
+
 	// Determine internal port based on app type
 	internalPort := DefaultInternalPort
 	if appType == "vite" {
@@ -53,22 +75,47 @@ func (d *DockerService) Deploy(
 		return false, "", fmt.Errorf("image build failed: %s", buildResult.Error)
 	}
 
-	// 3. Run the container
+	// 3. Run the container. Prefer the pushed digest reference when one
+	// is available so the run is deterministic and reproducible on any
+	// node that can reach the registry.
+	runImage := buildResult.ImageName
+	if buildResult.Digest != "" {
+		runImage = buildResult.RegistryRef + "@" + buildResult.Digest
+
+		registry := NewRegistry(config.Get().RegistryURL, config.Get().RegistryAuth)
+		if err := registry.PullImage(runImage); err != nil {
+			logging.StreamLog(buildID, fmt.Sprintf("Failed to pull %s, running local image instead: %v", runImage, err), logging.LogLevelWarning)
+			runImage = buildResult.ImageName
+		}
+	}
+
 	logging.StreamLog(buildID, "Starting container...", logging.LogLevelInfo)
-	config := ContainerConfig{
-		ProjectID:     projectID,
-		BuildID:       buildID,
-		ImageName:     buildResult.ImageName,
-		ContainerName: containerName,
-		HostPort:      hostPort,
-		InternalPort:  internalPort,
-		EnvVars:       envVars,
-		MemoryLimit:   DefaultMemoryLimit,
-		CPULimit:      DefaultCPULimit,
-		WorkDir:       sourceDir,
-	}
-
-	runResult := RunContainer(config)
+	containerConfig := ContainerConfig{
+		ProjectID:       projectID,
+		BuildID:         buildID,
+		ImageName:       runImage,
+		ContainerName:   containerName,
+		HostPort:        hostPort,
+		InternalPort:    internalPort,
+		EnvVars:         envVars,
+		MemoryLimit:     DefaultMemoryLimit,
+		CPULimit:        DefaultCPULimit,
+		WorkDir:         sourceDir,
+		RestartPolicy:   DefaultRestartPolicy,
+		StopGracePeriod: 10 * time.Second,
+		Security:        securityForDeploy(),
+	}
+	if appType != string(FrameworkVite) {
+		containerConfig.HealthCheck = &HealthCheckConfig{
+			Test:        []string{"CMD", "wget", "-qO-", fmt.Sprintf("http://localhost:%d/", internalPort)},
+			Interval:    10 * time.Second,
+			Timeout:     3 * time.Second,
+			Retries:     3,
+			StartPeriod: 10 * time.Second,
+		}
+	}
+
+	runResult := RunContainer(containerConfig)
 	if !runResult.Success {
 		logging.StreamLog(buildID, fmt.Sprintf("Container failed to start: %s", runResult.Error), logging.LogLevelError)
 		return false, "", fmt.Errorf("container failed to start: %s", runResult.Error)
@@ -88,6 +135,7 @@ func (d *DockerService) Deploy(
 	}
 
 	logging.StreamLog(buildID, "Container deployed successfully!", logging.LogLevelSuccess)
+	GetEventWatcher().ClearUnhealthy(projectID)
 
 	// 5. Cleanup old images
 	PruneProjectImages(projectID, 3)
@@ -98,6 +146,129 @@ func (d *DockerService) Deploy(
 	return true, runResult.ContainerID, nil
 }
 
+// deployBlueGreen builds and starts buildID's image under a temporary
+// "-candidate" name so it runs alongside the project's currently live
+// container, then waits for it to answer requests on hostPort before
+// reporting success. The live container is never touched here - a caller
+// switches traffic to hostPort (via the nginx upstream swap) and only
+// then calls PromoteCandidate to stop the old container and rename this
+// one into its place. A failed candidate is torn down immediately,
+// leaving the live container serving traffic the whole time.
+func (d *DockerService) deployBlueGreen(
+	projectID, buildID, sourceDir string,
+	hostPort int,
+	appType string,
+	envVars map[string]string,
+) (success bool, containerID string, err error) {
+	candidateName := candidateContainerName(projectID)
+
+	internalPort := DefaultInternalPort
+	if appType == "vite" {
+		internalPort = ViteInternalPort
+	}
+
+	// Clear out any stale candidate left behind by a previous failed
+	// attempt before starting a fresh one.
+	StopAndRemoveContainer(candidateName)
+
+	logging.StreamLog(buildID, "Building Docker image...", logging.LogLevelInfo)
+	buildResult := BuildImage(
+		projectID, buildID, sourceDir,
+		FrameworkType(appType), internalPort,
+		func(msg string) {
+			logging.StreamLog(buildID, msg, logging.LogLevelInfo)
+		},
+	)
+
+	if !buildResult.Success {
+		logging.StreamLog(buildID, fmt.Sprintf("Image build failed: %s", buildResult.Error), logging.LogLevelError)
+		return false, "", fmt.Errorf("image build failed: %s", buildResult.Error)
+	}
+
+	runImage := buildResult.ImageName
+	if buildResult.Digest != "" {
+		runImage = buildResult.RegistryRef + "@" + buildResult.Digest
+
+		registry := NewRegistry(config.Get().RegistryURL, config.Get().RegistryAuth)
+		if err := registry.PullImage(runImage); err != nil {
+			logging.StreamLog(buildID, fmt.Sprintf("Failed to pull %s, running local image instead: %v", runImage, err), logging.LogLevelWarning)
+			runImage = buildResult.ImageName
+		}
+	}
+
+	logging.StreamLog(buildID, "Starting candidate container alongside the live one...", logging.LogLevelInfo)
+	containerConfig := ContainerConfig{
+		ProjectID:       projectID,
+		BuildID:         buildID,
+		ImageName:       runImage,
+		ContainerName:   candidateName,
+		HostPort:        hostPort,
+		InternalPort:    internalPort,
+		EnvVars:         envVars,
+		MemoryLimit:     DefaultMemoryLimit,
+		CPULimit:        DefaultCPULimit,
+		WorkDir:         sourceDir,
+		RestartPolicy:   DefaultRestartPolicy,
+		StopGracePeriod: 10 * time.Second,
+		Security:        securityForDeploy(),
+	}
+	if appType != string(FrameworkVite) {
+		containerConfig.HealthCheck = &HealthCheckConfig{
+			Test:        []string{"CMD", "wget", "-qO-", fmt.Sprintf("http://localhost:%d/", internalPort)},
+			Interval:    10 * time.Second,
+			Timeout:     3 * time.Second,
+			Retries:     3,
+			StartPeriod: 10 * time.Second,
+		}
+	}
+
+	runResult := RunContainer(containerConfig)
+	if !runResult.Success {
+		logging.StreamLog(buildID, fmt.Sprintf("Candidate container failed to start: %s", runResult.Error), logging.LogLevelError)
+		StopAndRemoveContainer(candidateName)
+		return false, "", fmt.Errorf("container failed to start: %s", runResult.Error)
+	}
+
+	if !d.WaitForHealthy(hostPort, 30*time.Second) {
+		logs := GetContainerLogs(candidateName, 50)
+		logging.StreamLog(buildID, fmt.Sprintf("Candidate logs:\n%s", logs), logging.LogLevelWarning)
+		logging.StreamLog(buildID, "Candidate health check failed, leaving the live container in place", logging.LogLevelError)
+		StopAndRemoveContainer(candidateName)
+		return false, "", fmt.Errorf("health check failed")
+	}
+
+	logging.StreamLog(buildID, "Candidate container healthy, ready to switch traffic", logging.LogLevelSuccess)
+	GetEventWatcher().ClearUnhealthy(projectID)
+	return true, runResult.ContainerID, nil
+}
+
+// PromoteCandidate finishes a blue-green cutover once the caller has
+// confirmed traffic is flowing to the candidate container: it stops the
+// container the candidate is replacing and renames the candidate to the
+// stable name, so Stop/IsRunning/GetLogs keep working exactly as they do
+// for a "recreate" deploy. A no-op (returning true) if there's no
+// candidate, so it's safe to call after a "recreate" deploy too.
+func (d *DockerService) PromoteCandidate(projectID, buildID string) bool {
+	stableName := GetContainerName(projectID)
+	candidateName := candidateContainerName(projectID)
+
+	if !ContainerExists(candidateName) {
+		return true
+	}
+
+	d.StopLogStreaming(projectID)
+	StopAndRemoveContainer(stableName)
+
+	if !RenameContainer(candidateName, stableName) {
+		logging.StreamLog(buildID, "Traffic switched, but promoting the candidate container failed", logging.LogLevelError)
+		return false
+	}
+
+	d.StartLogStreaming(projectID, buildID)
+	PruneProjectImages(projectID, 3)
+	return true
+}
+
 // Stop stops a deployed container
 func (d *DockerService) Stop(projectID, buildID string) bool {
 	d.StopLogStreaming(projectID)
@@ -180,7 +351,10 @@ func (d *DockerService) StopLogStreaming(projectID string) {
 	}
 }
 
-// RecoverLogStreams recovers log streams for running containers on startup
+// RecoverLogStreams recovers log streams for running containers on
+// startup, and (re)subscribes the EventWatcher to Docker events so
+// containers that were already running before this process started are
+// still covered by auto-restart/unhealthy detection.
 func (d *DockerService) RecoverLogStreams() {
 	dockerLog("Recovering log streams for running containers...")
 	containers := ListRunningContainers()
@@ -191,9 +365,19 @@ func (d *DockerService) RecoverLogStreams() {
 		count++
 	}
 
+	GetEventWatcher().Start(context.Background())
+
 	dockerLog("Recovered log streams for %d containers", count)
 }
 
+// securityForDeploy returns the hardened container defaults, layering in
+// an operator-configured MAC profile when set.
+func securityForDeploy() *SecurityConfig {
+	sec := DefaultSecurity()
+	sec.MACProfile = config.Get().MACProfile
+	return sec
+}
+
 // Global instance
 var defaultDockerService *DockerService
 var dockerOnce sync.Once
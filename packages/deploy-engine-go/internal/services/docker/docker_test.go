@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thakurdotdev/deploy-engine/internal/services/docker/dockertest"
+)
+
+// withFakeDaemon points the package's shared client at a fresh dockertest
+// server for the duration of the test and restores the default client
+// afterwards, so tests can run in any order without dialing a real daemon.
+func withFakeDaemon(t *testing.T) *dockertest.Server {
+	t.Helper()
+	fake := dockertest.NewServer()
+	t.Cleanup(fake.Close)
+
+	cli, err := NewClientWithHost(fake.Host(), dockertest.APIVersion)
+	if err != nil {
+		t.Fatalf("NewClientWithHost: %v", err)
+	}
+	SetClient(cli)
+	t.Cleanup(func() { SetClient(nil) })
+
+	return fake
+}
+
+// listenOnFreePort starts a tiny HTTP server that always answers 200, the
+// way DockerService.WaitForHealthy expects a deployed app to, and returns
+// the port it's bound to.
+func listenOnFreePort(t *testing.T) int {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.Listener.Close()
+	srv.Listener = lis
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func TestDockerService_Deploy(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(t *testing.T, fake *dockertest.Server) int // returns hostPort
+		wantSuccess bool
+		wantRemoved bool // containerName should not exist after Deploy
+	}{
+		{
+			name: "happy path",
+			setup: func(t *testing.T, fake *dockertest.Server) int {
+				return listenOnFreePort(t)
+			},
+			wantSuccess: true,
+		},
+		{
+			name: "build failure",
+			setup: func(t *testing.T, fake *dockertest.Server) int {
+				fake.FailNext("build", 1)
+				return listenOnFreePort(t)
+			},
+			wantSuccess: false,
+		},
+		{
+			name: "health check failure rolls back the container",
+			setup: func(t *testing.T, fake *dockertest.Server) int {
+				// Nothing listens on this port, so DockerService's HTTP
+				// health check can never succeed.
+				lis, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatalf("listen: %v", err)
+				}
+				port := lis.Addr().(*net.TCPAddr).Port
+				lis.Close()
+				return port
+			},
+			wantSuccess: false,
+			wantRemoved: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := withFakeDaemon(t)
+			hostPort := tt.setup(t, fake)
+
+			d := NewDockerService()
+			projectID := "proj-" + tt.name
+			containerName := GetContainerName(projectID)
+
+			success, containerID, err := d.Deploy(projectID, "build-1", t.TempDir(), hostPort, "express", nil, StrategyRecreate)
+
+			if success != tt.wantSuccess {
+				t.Fatalf("Deploy() success = %v, want %v (err=%v)", success, tt.wantSuccess, err)
+			}
+			if tt.wantSuccess {
+				if containerID == "" {
+					t.Fatal("Deploy() returned no containerID on success")
+				}
+				if err != nil {
+					t.Fatalf("Deploy() unexpected error on success: %v", err)
+				}
+			} else if err == nil {
+				t.Fatal("Deploy() returned no error on failure")
+			}
+			if tt.wantRemoved && GetContainerInfo(containerName) != nil {
+				t.Fatalf("Deploy() left %s running after a failed deploy, want rollback to remove it", containerName)
+			}
+
+			d.StopLogStreaming(projectID)
+		})
+	}
+}
+
+func TestRecoverLogStreams(t *testing.T) {
+	withFakeDaemon(t)
+
+	projectID := "proj-recover"
+	buildID := "build-recover"
+	containerName := GetContainerName(projectID)
+
+	result := RunContainer(ContainerConfig{
+		ProjectID:     projectID,
+		BuildID:       buildID,
+		ImageName:     "thakur-deploy/recover:build",
+		ContainerName: containerName,
+		HostPort:      0,
+		InternalPort:  DefaultInternalPort,
+		RestartPolicy: DefaultRestartPolicy,
+	})
+	if !result.Success {
+		t.Fatalf("RunContainer() failed: %s", result.Error)
+	}
+
+	d := NewDockerService()
+	d.RecoverLogStreams()
+	t.Cleanup(func() { d.StopLogStreaming(projectID) })
+
+	if _, ok := d.logStreamers.Load(projectID); !ok {
+		t.Fatalf("RecoverLogStreams() did not start a log streamer for %s", projectID)
+	}
+}
@@ -0,0 +1,361 @@
+// Package dockertest is an in-process fake implementing the subset of the
+// Docker Engine API this module's docker.Client uses - image build,
+// container create/start/stop/rm/inspect/list/logs, and events - so
+// DockerService can be exercised in tests without a real daemon.
+package dockertest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// APIVersion is the Docker API version this fake speaks. It's passed to
+// docker.NewClientWithHost so the real client skips version negotiation
+// against it.
+const APIVersion = "1.43"
+
+// Server is the fake Docker daemon. Zero value is not usable - construct
+// with NewServer.
+type Server struct {
+	http *httptest.Server
+
+	mu         sync.Mutex
+	containers map[string]*container
+	nextID     int
+	logLines   map[string][]string // container name -> lines ContainerLogs/StreamLogs serve
+	failures   map[string]int      // endpoint key -> remaining forced failures
+
+	buildErr string // non-empty makes the next build fail with this message
+}
+
+type container struct {
+	id      string
+	name    string
+	image   string
+	labels  map[string]string
+	running bool
+	// health is "", "healthy", or "unhealthy". Empty means no healthcheck
+	// was configured, which docker.Client.WaitForHealthy treats as ready
+	// as soon as the container is running.
+	health string
+}
+
+// NewServer starts the fake and returns it. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		containers: make(map[string]*container),
+		logLines:   make(map[string][]string),
+		failures:   make(map[string]int),
+	}
+	s.http = httptest.NewServer(s.routes())
+	return s
+}
+
+// Close shuts down the underlying httptest server.
+func (s *Server) Close() {
+	s.http.Close()
+}
+
+// Host is the "tcp://host:port" URL to pass to docker.NewClientWithHost.
+func (s *Server) Host() string {
+	return strings.Replace(s.http.URL, "http://", "tcp://", 1)
+}
+
+// SetHealth marks containerName's configured health status, so a test can
+// make a just-started container report "healthy" or "unhealthy" the way a
+// real HEALTHCHECK would once the daemon runs it. Containers created
+// without calling this report no healthcheck at all (ready immediately).
+func (s *Server) SetHealth(containerName, health string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c := s.findByName(containerName); c != nil {
+		c.health = health
+	}
+}
+
+// SetLogs sets the log lines ContainerLogs/StreamLogs return for
+// containerName.
+func (s *Server) SetLogs(containerName string, lines []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logLines[containerName] = lines
+}
+
+// FailNext makes the next n requests to endpoint (one of "create",
+// "start", "stop", "build") fail - "create", "start" and "stop" with a
+// 500, "build" with a 200 whose JSON stream carries an error message, the
+// way the daemon reports a failed build - so tests can exercise
+// DockerService's failure/rollback handling without a real daemon ever
+// misbehaving. Counts are independent per endpoint.
+func (s *Server) FailNext(endpoint string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[endpoint] = n
+}
+
+// consumeFailure reports whether the given endpoint still has a forced
+// failure queued, decrementing the count if so.
+func (s *Server) consumeFailure(endpoint string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures[endpoint] <= 0 {
+		return false
+	}
+	s.failures[endpoint]--
+	return true
+}
+
+func (s *Server) findByName(name string) *container {
+	for _, c := range s.containers {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// findByRef resolves a container by ID or name, the way the real daemon's
+// endpoints accept either.
+func (s *Server) findByRef(ref string) *container {
+	if c, ok := s.containers[ref]; ok {
+		return c
+	}
+	return s.findByName(ref)
+}
+
+func (s *Server) routes() http.Handler {
+	r := chi.NewRouter()
+	prefix := "/v" + APIVersion
+
+	r.Get("/_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", APIVersion)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.Route(prefix, func(r chi.Router) {
+		r.Post("/containers/create", s.handleCreate)
+		r.Post("/containers/{ref}/start", s.handleStart)
+		r.Post("/containers/{ref}/stop", s.handleStop)
+		r.Delete("/containers/{ref}", s.handleRemove)
+		r.Get("/containers/{ref}/json", s.handleInspect)
+		r.Get("/containers/{ref}/logs", s.handleLogs)
+		r.Get("/containers/json", s.handleList)
+		r.Get("/events", s.handleEvents)
+		r.Post("/build", s.handleBuild)
+	})
+
+	return r
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if s.consumeFailure("create") {
+		http.Error(w, "forced failure", http.StatusInternalServerError)
+		return
+	}
+
+	var body struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	name := strings.TrimPrefix(r.URL.Query().Get("name"), "/")
+
+	s.mu.Lock()
+	s.nextID++
+	id := fmt.Sprintf("fake-%d", s.nextID)
+	s.containers[id] = &container{
+		id:     id,
+		name:   name,
+		image:  body.Image,
+		labels: body.Labels,
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, map[string]string{"Id": id})
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	if s.consumeFailure("start") {
+		http.Error(w, "forced failure", http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	c := s.findByRef(chi.URLParam(r, "ref"))
+	if c != nil {
+		c.running = true
+	}
+	s.mu.Unlock()
+
+	if c == nil {
+		http.Error(w, "no such container", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if s.consumeFailure("stop") {
+		http.Error(w, "forced failure", http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	c := s.findByRef(chi.URLParam(r, "ref"))
+	if c != nil {
+		c.running = false
+	}
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRemove(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c := s.findByRef(chi.URLParam(r, "ref"))
+	if c != nil {
+		delete(s.containers, c.id)
+	}
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleInspect(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c := s.findByRef(chi.URLParam(r, "ref"))
+	s.mu.Unlock()
+
+	if c == nil {
+		http.Error(w, "no such container", http.StatusNotFound)
+		return
+	}
+
+	status := "exited"
+	if c.running {
+		status = "running"
+	}
+
+	resp := map[string]interface{}{
+		"Id":   c.id,
+		"Name": "/" + c.name,
+		"State": map[string]interface{}{
+			"Status":  status,
+			"Running": c.running,
+		},
+	}
+	if c.health != "" {
+		resp["State"].(map[string]interface{})["Health"] = map[string]string{"Status": c.health}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type summary struct {
+		Id     string            `json:"Id"`
+		Names  []string          `json:"Names"`
+		Labels map[string]string `json:"Labels"`
+	}
+	list := make([]summary, 0, len(s.containers))
+	for _, c := range s.containers {
+		if !c.running {
+			continue
+		}
+		list = append(list, summary{Id: c.id, Names: []string{"/" + c.name}, Labels: c.labels})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleLogs writes the configured log lines framed as the daemon's
+// multiplexed stdout/stderr stream, the format docker/pkg/stdcopy expects
+// to demultiplex on the client side. A positive "tail" trims to the last N
+// lines; any other value, including "0", serves every configured line
+// (the real daemon serves none for "0").
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c := s.findByRef(chi.URLParam(r, "ref"))
+	var lines []string
+	if c != nil {
+		lines = s.logLines[c.name]
+	}
+	s.mu.Unlock()
+
+	if tail := parseTail(r.URL.Query().Get("tail")); tail > 0 && tail < len(lines) {
+		lines = lines[len(lines)-tail:]
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+	w.WriteHeader(http.StatusOK)
+	for _, line := range lines {
+		writeStdcopyFrame(w, 1, line+"\n")
+	}
+
+	if r.URL.Query().Get("follow") != "true" {
+		return
+	}
+	// Followed streams just end after replaying the configured lines -
+	// tests that need live-appended lines call SetLogs before
+	// StreamContainerLogs reads them.
+}
+
+// writeStdcopyFrame writes one frame in docker's multiplexed log format:
+// an 8-byte header (stream type, 3 reserved bytes, big-endian uint32
+// length) followed by the payload.
+func writeStdcopyFrame(w http.ResponseWriter, stream byte, payload string) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	w.Write(header)
+	w.Write([]byte(payload))
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	// The fake never emits any events. Keeping the connection open (rather
+	// than closing immediately) matches the daemon's behavior of holding
+	// /events open until the client disconnects.
+	<-r.Context().Done()
+}
+
+func (s *Server) handleBuild(w http.ResponseWriter, r *http.Request) {
+	if s.consumeFailure("build") {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"errorDetail": map[string]string{"message": "forced build failure"},
+			"error":       "forced build failure",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.Encode(map[string]string{"stream": "Step 1/1 : FROM scratch\n"})
+	enc.Encode(map[string]string{"stream": "Successfully built fakeimage\n"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func parseTail(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
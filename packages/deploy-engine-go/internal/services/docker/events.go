@@ -0,0 +1,214 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/thakurdotdev/deploy-engine/internal/logging"
+)
+
+// maxAutoRestarts bounds how many times the watcher will restart a
+// container that keeps dying within autoRestartWindow before giving up
+// and marking the deployment unhealthy instead.
+const (
+	maxAutoRestarts   = 3
+	autoRestartWindow = 10 * time.Minute
+)
+
+// EventWatcher subscribes to the Docker Events API and reacts to
+// unexpected exits of containers this system manages: it streams the
+// exit reason and recent logs through logging.StreamLog, auto-restarts
+// the container with a bounded retry budget, and marks the deployment
+// unhealthy once that budget is exhausted. This replaces relying solely
+// on WaitForHealthy's one-shot polling with a push model that also
+// covers crashes long after a deploy finished.
+type EventWatcher struct {
+	mu        sync.Mutex
+	expected  map[string]bool        // container names whose next "die" is an intentional stop
+	restarts  map[string][]time.Time // containerName -> recent auto-restart timestamps
+	unhealthy map[string]bool        // projectID -> watcher gave up auto-restarting it
+	cancel    context.CancelFunc
+}
+
+func NewEventWatcher() *EventWatcher {
+	return &EventWatcher{
+		expected:  make(map[string]bool),
+		restarts:  make(map[string][]time.Time),
+		unhealthy: make(map[string]bool),
+	}
+}
+
+var (
+	defaultWatcher *EventWatcher
+	watcherOnce    sync.Once
+)
+
+// GetEventWatcher returns the process-wide EventWatcher instance.
+func GetEventWatcher() *EventWatcher {
+	watcherOnce.Do(func() {
+		defaultWatcher = NewEventWatcher()
+	})
+	return defaultWatcher
+}
+
+// ExpectStop marks containerName's next "die" event as intentional, so a
+// deliberate Stop (or a blue-green PromoteCandidate swap) isn't mistaken
+// for a crash the watcher should try to recover.
+func (w *EventWatcher) ExpectStop(containerName string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expected[containerName] = true
+}
+
+// Start begins watching Docker events in the background until ctx is
+// cancelled. Safe to call more than once - later calls are no-ops, so
+// both Server.Start and RecoverLogStreams can call it unconditionally.
+func (w *EventWatcher) Start(ctx context.Context) {
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.mu.Unlock()
+		return
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+	w.mu.Unlock()
+
+	cli, err := getClient()
+	if err != nil {
+		dockerLog("event watcher disabled, no docker client: %v", err)
+		return
+	}
+
+	msgs, errs := cli.SubscribeEvents(watchCtx)
+	go func() {
+		for {
+			select {
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				w.handle(msg)
+			case err, ok := <-errs:
+				if !ok {
+					return
+				}
+				if err != nil {
+					dockerLog("docker event stream error: %v", err)
+				}
+			case <-watchCtx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop cancels the background event subscription.
+func (w *EventWatcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+}
+
+func (w *EventWatcher) handle(msg events.Message) {
+	if msg.Type != events.ContainerEventType {
+		return
+	}
+
+	containerName := msg.Actor.Attributes["name"]
+	projectID := msg.Actor.Attributes["thakur.projectId"]
+	buildID := msg.Actor.Attributes["thakur.buildId"]
+
+	switch {
+	case msg.Action == "die":
+		w.handleDie(containerName, projectID, buildID)
+	case msg.Action == "oom":
+		logging.StreamLog(buildID, fmt.Sprintf("Container %s was OOM-killed", containerName), logging.LogLevelError)
+	case string(msg.Action) == "health_status: unhealthy":
+		logging.StreamLog(buildID, fmt.Sprintf("Container %s reported unhealthy", containerName), logging.LogLevelWarning)
+	}
+}
+
+// handleDie reacts to a container dying that ExpectStop didn't mark as
+// intentional: it streams the exit reason and last 100 log lines, then
+// either restarts the container (within its retry budget) or marks
+// projectID unhealthy.
+func (w *EventWatcher) handleDie(containerName, projectID, buildID string) {
+	w.mu.Lock()
+	if w.expected[containerName] {
+		delete(w.expected, containerName)
+		w.mu.Unlock()
+		return
+	}
+	w.mu.Unlock()
+
+	if projectID == "" {
+		return
+	}
+
+	logs := GetContainerLogs(containerName, 100)
+	logging.StreamLog(buildID, fmt.Sprintf("Container %s exited unexpectedly, last logs:\n%s", containerName, logs), logging.LogLevelError)
+
+	if !w.allowRestart(containerName) {
+		logging.StreamLog(buildID, fmt.Sprintf("Container %s exceeded %d auto-restarts within %s, marking deployment unhealthy", containerName, maxAutoRestarts, autoRestartWindow), logging.LogLevelError)
+		w.mu.Lock()
+		w.unhealthy[projectID] = true
+		w.mu.Unlock()
+		return
+	}
+
+	cli, err := getClient()
+	if err != nil {
+		return
+	}
+	if err := cli.StartContainer(context.Background(), containerName); err != nil {
+		logging.StreamLog(buildID, fmt.Sprintf("Auto-restart of %s failed: %v", containerName, err), logging.LogLevelError)
+		return
+	}
+	logging.StreamLog(buildID, fmt.Sprintf("Container %s auto-restarted", containerName), logging.LogLevelWarning)
+}
+
+// allowRestart reports whether containerName is still within its
+// auto-restart budget, pruning attempts older than autoRestartWindow
+// before counting.
+func (w *EventWatcher) allowRestart(containerName string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cutoff := time.Now().Add(-autoRestartWindow)
+	var recent []time.Time
+	for _, t := range w.restarts[containerName] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= maxAutoRestarts {
+		w.restarts[containerName] = recent
+		return false
+	}
+
+	w.restarts[containerName] = append(recent, time.Now())
+	return true
+}
+
+// IsUnhealthy reports whether the watcher has given up auto-restarting
+// projectID's container and marked the deployment unhealthy.
+func (w *EventWatcher) IsUnhealthy(projectID string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.unhealthy[projectID]
+}
+
+// ClearUnhealthy resets the unhealthy flag for projectID, e.g. once a
+// fresh deploy has replaced the failing container.
+func (w *EventWatcher) ClearUnhealthy(projectID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.unhealthy, projectID)
+}
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,6 +9,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/thakurdotdev/deploy-engine/internal/config"
 )
 
 type FrameworkType string
@@ -48,6 +51,13 @@ func HasStartScript(sourceDir string) bool {
 	return strings.Contains(string(data), `"start"`)
 }
 
+// defaultHealthcheck returns a HEALTHCHECK instruction that probes the
+// app's own port, so the daemon can tell us when a freshly started
+// container is actually serving requests.
+func defaultHealthcheck(internalPort int) string {
+	return fmt.Sprintf("HEALTHCHECK --interval=10s --timeout=3s --start-period=10s --retries=3 CMD wget -qO- http://localhost:%d/ || exit 1", internalPort)
+}
+
 // GenerateDockerfile creates a Dockerfile for the given framework
 func GenerateDockerfile(framework FrameworkType, internalPort int, entryFile string) string {
 	// Determine CMD based on entry point
@@ -58,7 +68,8 @@ func GenerateDockerfile(framework FrameworkType, internalPort int, entryFile str
 
 	switch framework {
 	case FrameworkVite:
-		// Static sites use nginx:alpine
+		// Static sites use nginx:alpine, which already does its own
+		// health checking at the orchestration layer.
 		return `FROM nginx:alpine
 COPY dist/ /usr/share/nginx/html
 EXPOSE 80
@@ -73,11 +84,13 @@ COPY . .
 
 FROM oven/bun:1-alpine
 WORKDIR /app
-COPY --from=builder /app .
+COPY --from=builder --chown=1000:1000 /app .
 ENV NODE_ENV=production
 ENV PORT=%d
 EXPOSE %d
-%s`, internalPort, internalPort, cmd)
+USER 1000:1000
+%s
+%s`, internalPort, internalPort, defaultHealthcheck(internalPort), cmd)
 
 	default:
 		// Backend frameworks (express, hono, elysia)
@@ -89,11 +102,13 @@ COPY . .
 
 FROM oven/bun:1-alpine
 WORKDIR /app
-COPY --from=builder /app .
+COPY --from=builder --chown=1000:1000 /app .
 ENV NODE_ENV=production
 ENV PORT=%d
 EXPOSE %d
-%s`, internalPort, internalPort, cmd)
+USER 1000:1000
+%s
+%s`, internalPort, internalPort, defaultHealthcheck(internalPort), cmd)
 	}
 }
 
@@ -195,46 +210,73 @@ func BuildImage(
 		generatedDockerfile = true
 	}
 
-	// Build the image
+	// Build the image via the Docker Engine API, warming BuildKit's layer
+	// cache from the project's last successful builds when available.
+	cache := getBuildCache().CacheFrom(projectID)
+	if len(cache) > 0 {
+		onLog(fmt.Sprintf("Using cache from: %s", strings.Join(cache, ", ")))
+	}
+
 	onLog(fmt.Sprintf("Building Docker image: %s", imageName))
-	exitCode, err := ExecDockerWithStream(
-		[]string{"build", "-t", imageName, sourceDir},
-		onLog,
-	)
+	cli, err := getClient()
+	if err == nil {
+		err = cli.BuildImage(context.Background(), sourceDir, imageName, cache, onLog)
+	}
 
 	// Cleanup generated Dockerfile
 	if generatedDockerfile {
 		os.Remove(dockerfilePath)
 	}
 
-	if exitCode != 0 || err != nil {
-		errMsg := "Docker build failed"
+	if err != nil {
+		return BuildResult{Success: false, ImageName: imageName, Error: err.Error()}
+	}
+
+	getBuildCache().RecordSuccess(projectID, imageName)
+
+	onLog(fmt.Sprintf("Image built successfully: %s", imageName))
+	result := BuildResult{Success: true, ImageName: imageName}
+
+	// Push to the private registry when one is configured, so the image
+	// can be scheduled on any node in the fleet by digest.
+	if registryURL := config.Get().RegistryURL; registryURL != "" {
+		registryRef := registryURL + "/" + imageName
+		onLog(fmt.Sprintf("Pushing %s to registry...", registryRef))
+
+		digest, err := NewRegistry(registryURL, config.Get().RegistryAuth).PushImage(imageName, registryRef)
 		if err != nil {
-			errMsg = err.Error()
+			onLog(fmt.Sprintf("Registry push failed, continuing with local image: %v", err))
+			return result
 		}
-		return BuildResult{Success: false, ImageName: imageName, Error: errMsg}
+
+		onLog(fmt.Sprintf("Pushed %s", digest))
+		result.RegistryRef = registryRef
+		result.Digest = digest
 	}
 
-	onLog(fmt.Sprintf("Image built successfully: %s", imageName))
-	return BuildResult{Success: true, ImageName: imageName}
+	return result
 }
 
 // RemoveImage deletes a Docker image
 func RemoveImage(imageName string) bool {
-	result := ExecDocker("rmi", "-f", imageName)
-	return result.ExitCode == 0
+	cli, err := getClient()
+	if err != nil {
+		return false
+	}
+	return cli.RemoveImage(context.Background(), imageName) == nil
 }
 
 // PruneProjectImages keeps only the latest N images for a project
 func PruneProjectImages(projectID string, keepCount int) {
 	prefix := "thakur-deploy/" + projectID[:min(8, len(projectID))]
 
-	result := ExecDocker(
-		"images", "--format", "{{.Repository}}:{{.Tag}} {{.CreatedAt}}",
-		"--filter", fmt.Sprintf("reference=%s:*", prefix),
-	)
+	cli, err := getClient()
+	if err != nil {
+		return
+	}
 
-	if result.ExitCode != 0 || result.Stdout == "" {
+	images, err := cli.ListProjectImages(context.Background(), prefix)
+	if err != nil || len(images) == 0 {
 		return
 	}
 
@@ -243,31 +285,22 @@ func PruneProjectImages(projectID string, keepCount int) {
 		date time.Time
 	}
 
-	var images []imageInfo
-	for _, line := range strings.Split(result.Stdout, "\n") {
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) < 2 {
-			continue
-		}
-		// Parse date (format: 2024-01-01 12:00:00 +0000 UTC)
-		t, err := time.Parse("2006-01-02 15:04:05 -0700 MST", parts[1])
-		if err != nil {
+	var infos []imageInfo
+	for _, img := range images {
+		if len(img.RepoTags) == 0 {
 			continue
 		}
-		images = append(images, imageInfo{name: parts[0], date: t})
+		infos = append(infos, imageInfo{name: img.RepoTags[0], date: time.Unix(img.Created, 0)})
 	}
 
 	// Sort by date descending
-	sort.Slice(images, func(i, j int) bool {
-		return images[i].date.After(images[j].date)
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].date.After(infos[j].date)
 	})
 
 	// Remove old images beyond keepCount
-	for i := keepCount; i < len(images); i++ {
-		RemoveImage(images[i].name)
+	for i := keepCount; i < len(infos); i++ {
+		RemoveImage(infos[i].name)
 	}
 }
 
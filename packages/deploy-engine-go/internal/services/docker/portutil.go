@@ -0,0 +1,60 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// natPortSet builds the ExposedPorts set for a single TCP port.
+func natPortSet(internalPort int) nat.PortSet {
+	port := nat.Port(fmt.Sprintf("%d/tcp", internalPort))
+	return nat.PortSet{port: struct{}{}}
+}
+
+// natPortMap builds the host<->container port binding for a single TCP port.
+func natPortMap(hostPort, internalPort int) nat.PortMap {
+	port := nat.Port(fmt.Sprintf("%d/tcp", internalPort))
+	return nat.PortMap{
+		port: []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: strconv.Itoa(hostPort)}},
+	}
+}
+
+// parseMemoryLimit converts a docker-style size string ("512m", "1g") into
+// bytes, returning 0 (no limit) if it can't be parsed.
+func parseMemoryLimit(limit string) int64 {
+	limit = strings.TrimSpace(strings.ToLower(limit))
+	if limit == "" {
+		return 0
+	}
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(limit, "g"):
+		multiplier = 1 << 30
+		limit = strings.TrimSuffix(limit, "g")
+	case strings.HasSuffix(limit, "m"):
+		multiplier = 1 << 20
+		limit = strings.TrimSuffix(limit, "m")
+	case strings.HasSuffix(limit, "k"):
+		multiplier = 1 << 10
+		limit = strings.TrimSuffix(limit, "k")
+	}
+
+	value, err := strconv.ParseFloat(limit, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(value * float64(multiplier))
+}
+
+// parseCPULimit converts a fractional CPU count ("0.5") into NanoCPUs.
+func parseCPULimit(limit string) int64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(limit), 64)
+	if err != nil {
+		return 0
+	}
+	return int64(value * 1e9)
+}
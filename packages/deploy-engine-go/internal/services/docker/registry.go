@@ -0,0 +1,221 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// Registry pushes/pulls images to a private registry, so a build made on
+// one node can be run deterministically on any other node in the fleet.
+type Registry struct {
+	URL  string
+	Auth string // optional static "user:pass" override
+}
+
+// NewRegistry creates a Registry client for url, using auth (if non-empty)
+// instead of resolving credentials from ~/.docker/config.json.
+func NewRegistry(url, auth string) *Registry {
+	return &Registry{URL: url, Auth: auth}
+}
+
+// PushImage tags the local imageName as registryRef (e.g.
+// "registry.thakur.dev/thakur-deploy/abcd1234:ef012345"), pushes it, and
+// returns the immutable manifest digest so the image can be referenced as
+// registryRef@digest from any node.
+func (r *Registry) PushImage(imageName, registryRef string) (string, error) {
+	cli, err := getClient()
+	if err != nil {
+		return "", err
+	}
+	ctx := context.Background()
+
+	if err := cli.api.ImageTag(ctx, imageName, registryRef); err != nil {
+		return "", fmt.Errorf("failed to tag %s as %s: %w", imageName, registryRef, err)
+	}
+
+	authStr, err := r.resolveAuthHeader()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve registry credentials: %w", err)
+	}
+
+	stream, err := cli.api.ImagePush(ctx, registryRef, dockertypes.ImagePushOptions{RegistryAuth: authStr})
+	if err != nil {
+		return "", fmt.Errorf("push failed: %w", err)
+	}
+	defer stream.Close()
+
+	digest, err := readPushDigest(stream)
+	if err != nil {
+		return "", fmt.Errorf("push of %s failed: %w", registryRef, err)
+	}
+	return digest, nil
+}
+
+// PullImage pulls ref - typically a "registry/repo@sha256:..." digest
+// reference - so a fresh node can run the exact image that was built and
+// pushed elsewhere.
+func (r *Registry) PullImage(ref string) error {
+	cli, err := getClient()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	authStr, err := r.resolveAuthHeader()
+	if err != nil {
+		return fmt.Errorf("failed to resolve registry credentials: %w", err)
+	}
+
+	stream, err := cli.api.ImagePull(ctx, ref, dockertypes.ImagePullOptions{RegistryAuth: authStr})
+	if err != nil {
+		return fmt.Errorf("pull of %s failed: %w", ref, err)
+	}
+	defer stream.Close()
+
+	return streamJSONMessages(stream, nil)
+}
+
+// readPushDigest scans the push response's JSON message stream for the
+// `aux` payload carrying the resulting manifest digest.
+func readPushDigest(r io.Reader) (string, error) {
+	decoder := json.NewDecoder(r)
+	var digest string
+
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		if msg.Error != nil {
+			return "", msg.Error
+		}
+		if msg.Aux == nil {
+			continue
+		}
+
+		var aux struct {
+			Tag    string `json:"Tag"`
+			Digest string `json:"Digest"`
+		}
+		if json.Unmarshal(*msg.Aux, &aux) == nil && aux.Digest != "" {
+			digest = aux.Digest
+		}
+	}
+
+	if digest == "" {
+		return "", fmt.Errorf("push completed without reporting a digest")
+	}
+	return digest, nil
+}
+
+// resolveAuthHeader builds the base64-encoded X-Registry-Auth value the
+// Docker Engine API expects, preferring an explicit override, then
+// docker-credential-* helpers, then a plaintext ~/.docker/config.json
+// entry, and finally falling back to anonymous access.
+func (r *Registry) resolveAuthHeader() (string, error) {
+	if r.Auth != "" {
+		username, secret, ok := strings.Cut(r.Auth, ":")
+		if !ok {
+			return "", fmt.Errorf("RegistryAuth must be in \"user:pass\" form")
+		}
+		return encodeAuth(username, secret)
+	}
+
+	cfg, err := loadDockerConfig()
+	if err != nil {
+		// No ~/.docker/config.json (or unreadable) - push/pull anonymously.
+		return "", nil
+	}
+
+	helper := cfg.CredHelpers[r.URL]
+	if helper == "" {
+		helper = cfg.CredsStore
+	}
+	if helper != "" {
+		if username, secret, err := credentialHelperGet(helper, r.URL); err == nil {
+			return encodeAuth(username, secret)
+		}
+	}
+
+	if entry, ok := cfg.Auths[r.URL]; ok && entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err == nil {
+			if username, secret, ok := strings.Cut(string(decoded), ":"); ok {
+				return encodeAuth(username, secret)
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// dockerConfigFile mirrors the subset of ~/.docker/config.json we rely on.
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+func loadDockerConfig() (*dockerConfigFile, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(home, ".docker", "config.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg dockerConfigFile
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// credentialHelperGet shells out to docker-credential-<helper>, the same
+// protocol the docker CLI itself uses to fetch registry credentials.
+func credentialHelperGet(helper, registry string) (string, string, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("docker-credential-%s get failed: %w", helper, err)
+	}
+
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return "", "", err
+	}
+	return resp.Username, resp.Secret, nil
+}
+
+func encodeAuth(username, secret string) (string, error) {
+	raw, err := json.Marshal(dockertypes.AuthConfig{Username: username, Password: secret})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(raw), nil
+}
@@ -1,17 +1,65 @@
 package docker
 
+import "time"
+
 // Container configuration for running
 type ContainerConfig struct {
-	ProjectID    string
-	BuildID      string
-	ImageName    string
+	ProjectID     string
+	BuildID       string
+	ImageName     string
 	ContainerName string
-	HostPort     int
-	InternalPort int
-	EnvVars      map[string]string
-	MemoryLimit  string
-	CPULimit     string
-	WorkDir      string
+	HostPort      int
+	InternalPort  int
+	EnvVars       map[string]string
+	MemoryLimit   string
+	CPULimit      string
+	WorkDir       string
+
+	HealthCheck     *HealthCheckConfig
+	RestartPolicy   string // "no" | "on-failure:N" | "always" | "unless-stopped"
+	StopGracePeriod time.Duration
+	Security        *SecurityConfig
+}
+
+// SecurityConfig hardens a container's runtime privileges. DefaultSecurity
+// returns the values every user workload gets unless a caller overrides
+// them.
+type SecurityConfig struct {
+	CapDrop         []string
+	CapAdd          []string
+	NoNewPrivileges bool
+	ReadOnlyRootfs  bool
+	Tmpfs           map[string]string // mount path -> mount options, e.g. "/tmp" -> "rw,size=64m"
+	PidsLimit       int64
+	User            string // "uid:gid"
+	MACProfile      string // "apparmor=<profile>" or "label=type:<type>"
+}
+
+// DefaultSecurity returns the hardened defaults applied to every
+// user-workload container: no capabilities beyond binding low ports, no
+// privilege escalation, a read-only rootfs with a writable /tmp, a
+// non-root UID, and a bounded process count.
+func DefaultSecurity() *SecurityConfig {
+	return &SecurityConfig{
+		CapDrop:         []string{"ALL"},
+		CapAdd:          []string{"NET_BIND_SERVICE"},
+		NoNewPrivileges: true,
+		ReadOnlyRootfs:  true,
+		Tmpfs:           map[string]string{"/tmp": "rw,size=64m"},
+		PidsLimit:       256,
+		User:            "1000:1000",
+	}
+}
+
+// HealthCheckConfig mirrors the docker HEALTHCHECK instruction so
+// RunContainer can pass --health-cmd/--health-interval/... and the caller
+// can gate readiness on the daemon reporting the container healthy.
+type HealthCheckConfig struct {
+	Test        []string // e.g. []string{"CMD", "wget", "-qO-", "http://localhost:3000/"}
+	Interval    time.Duration
+	Timeout     time.Duration
+	Retries     int
+	StartPeriod time.Duration
 }
 
 // Container state info
@@ -26,6 +74,13 @@ type BuildResult struct {
 	Success   bool
 	ImageName string
 	Error     string
+
+	// RegistryRef/Digest are set when Config.RegistryURL is configured and
+	// the build was pushed, giving callers a digest reference
+	// ("registry/thakur-deploy/xxx@sha256:...") that any node can pull and
+	// run deterministically.
+	RegistryRef string
+	Digest      string
 }
 
 // Container run result
@@ -37,10 +92,11 @@ type RunResult struct {
 
 // Default resource limits
 const (
-	DefaultMemoryLimit = "512m"
-	DefaultCPULimit    = "0.5"
-	DefaultInternalPort = 3000
-	ViteInternalPort    = 80
+	DefaultMemoryLimit   = "512m"
+	DefaultCPULimit      = "0.5"
+	DefaultInternalPort  = 3000
+	ViteInternalPort     = 80
+	DefaultRestartPolicy = "unless-stopped"
 )
 
 // Container naming: thakur-{projectId[:8]}
@@ -51,6 +107,24 @@ func GetContainerName(projectID string) string {
 	return "thakur-" + projectID
 }
 
+// candidateContainerName is the temporary name a blue-green deploy's new
+// container runs under until PromoteCandidate renames it to the stable
+// name, so it can run alongside the container it's replacing without a
+// name collision.
+func candidateContainerName(projectID string) string {
+	return GetContainerName(projectID) + "-candidate"
+}
+
+// Deploy strategies accepted by ActivateRequest.Strategy: "recreate" stops
+// the previous container before starting the new one (the default);
+// "blue-green" starts the new container alongside the old one and only
+// cuts traffic over - and stops the old one - once the new one is
+// confirmed healthy.
+const (
+	StrategyRecreate  = "recreate"
+	StrategyBlueGreen = "blue-green"
+)
+
 // Image naming: thakur-deploy/{projectId[:8]}:{buildId[:8]}
 func GetImageName(projectID, buildID string) string {
 	pid := projectID
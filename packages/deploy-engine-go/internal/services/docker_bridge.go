@@ -1,12 +1,17 @@
 package services
 
 import (
+	"github.com/thakurdotdev/deploy-engine/internal/config"
 	"github.com/thakurdotdev/deploy-engine/internal/services/docker"
+	"github.com/thakurdotdev/deploy-engine/internal/services/podman"
 )
 
-// DockerServiceInterface defines the Docker service contract
-type DockerServiceInterface interface {
-	Deploy(projectID, buildID, sourceDir string, hostPort int, appType string, envVars map[string]string) (bool, string, error)
+// ContainerRuntime is the contract both the Docker and Podman backends
+// implement, so DeployService can run against whichever one
+// config.Get().Runtime selects without caring which daemon is underneath.
+type ContainerRuntime interface {
+	Deploy(projectID, buildID, sourceDir string, hostPort int, appType string, envVars map[string]string, strategy string) (bool, string, error)
+	PromoteCandidate(projectID, buildID string) bool
 	Stop(projectID, buildID string) bool
 	Cleanup(projectID string, buildIDs []string)
 	RecoverLogStreams()
@@ -14,17 +19,43 @@ type DockerServiceInterface interface {
 	GetLogs(projectID string, tail int) string
 }
 
-// GetDockerService returns the Docker service singleton
-func GetDockerService() DockerServiceInterface {
+// GetDockerService returns the configured ContainerRuntime singleton -
+// docker.GetDockerService() for config.Runtime == "docker" (the default),
+// or podman.GetPodmanService() for "podman". Named GetDockerService rather
+// than something runtime-neutral because nearly every caller predates
+// Podman support and already spells it that way.
+func GetDockerService() ContainerRuntime {
+	if config.Get().Runtime == "podman" {
+		return podman.GetPodmanService()
+	}
 	return docker.GetDockerService()
 }
 
-// RecoverDockerLogStreams recovers log streams for running containers on startup
+// RecoverDockerLogStreams calls the configured runtime's RecoverLogStreams
+// on startup. Under Docker that recovers log streams for running containers
+// and re-subscribes the event watcher, so they stay covered by
+// auto-restart/unhealthy detection across a restart of this process; the
+// Podman backend only subscribes to events and logs container exits.
 func RecoverDockerLogStreams() {
-	docker.GetDockerService().RecoverLogStreams()
+	GetDockerService().RecoverLogStreams()
 }
 
-// IsDockerAvailable checks if Docker daemon is running
+// IsDockerAvailable checks if the configured container runtime's daemon is
+// reachable.
 func IsDockerAvailable() bool {
+	if config.Get().Runtime == "podman" {
+		return podman.IsAvailable()
+	}
 	return docker.IsDockerAvailable()
 }
+
+// IsDeploymentUnhealthy reports whether the Docker event watcher has given
+// up auto-restarting projectID's container and marked its deployment
+// unhealthy. Only the Docker backend tracks this today; Podman always
+// reports healthy until it grows the same auto-restart budget.
+func IsDeploymentUnhealthy(projectID string) bool {
+	if config.Get().Runtime == "podman" {
+		return false
+	}
+	return docker.GetEventWatcher().IsUnhealthy(projectID)
+}
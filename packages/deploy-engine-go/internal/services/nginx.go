@@ -2,11 +2,14 @@ package services
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/thakurdotdev/deploy-engine/internal/config"
 )
@@ -50,6 +53,24 @@ func (n *NginxService) IsSubdomainAllowed(sub string) bool {
 	return true
 }
 
+// upstreamName is the nginx upstream block name for a subdomain. It lives
+// in its own file so SwitchUpstream can rewrite it without touching the
+// server block.
+func upstreamName(sub string) string {
+	return sub + "_backend"
+}
+
+func (n *NginxService) upstreamPath(sub string) string {
+	return filepath.Join(nginxAvailableDir, sub+"_upstream.conf")
+}
+
+func (n *NginxService) GenerateUpstream(sub string, port int) string {
+	return fmt.Sprintf("upstream %s {\n    server 127.0.0.1:%d;\n}\n", upstreamName(sub), port)
+}
+
+// GenerateConfig emits a server block that proxies to the subdomain's
+// upstream by name rather than a hardcoded port, so a later SwitchUpstream
+// can move traffic to a new container/process without rewriting this file.
 func (n *NginxService) GenerateConfig(sub string, port int) string {
 	return fmt.Sprintf(`
 server {
@@ -70,7 +91,7 @@ server {
     ssl_ciphers HIGH:!aNULL:!MD5;
 
     location / {
-        proxy_pass http://localhost:%d;
+        proxy_pass http://%s;
         proxy_http_version 1.1;
 
         proxy_set_header Upgrade $http_upgrade;
@@ -85,7 +106,7 @@ server {
         proxy_send_timeout 300;
     }
 }
-`, sub, n.baseDomain, sub, n.baseDomain, n.baseDomain, n.baseDomain, port)
+`, sub, n.baseDomain, sub, n.baseDomain, n.baseDomain, n.baseDomain, upstreamName(sub))
 }
 
 func (n *NginxService) CreateConfig(sub string, port int) error {
@@ -93,6 +114,11 @@ func (n *NginxService) CreateConfig(sub string, port int) error {
 		return fmt.Errorf("invalid or reserved subdomain: %s", sub)
 	}
 
+	upstream := n.upstreamPath(sub)
+	if err := os.WriteFile(upstream, []byte(n.GenerateUpstream(sub, port)), 0644); err != nil {
+		return fmt.Errorf("failed to write upstream config: %w", err)
+	}
+
 	available := filepath.Join(nginxAvailableDir, sub+".conf")
 	enabled := filepath.Join(nginxEnabledDir, sub+".conf")
 
@@ -109,12 +135,94 @@ func (n *NginxService) CreateConfig(sub string, port int) error {
 	return n.Reload()
 }
 
+// ConfigExists reports whether sub already has a server block on disk, so
+// ConfigureNginx can tell a first deploy (which must use CreateConfig)
+// from a later one that's eligible for a blue-green SwitchUpstream.
+func (n *NginxService) ConfigExists(sub string) bool {
+	_, err := os.Stat(filepath.Join(nginxAvailableDir, sub+".conf"))
+	return err == nil
+}
+
+// SwitchUpstream health-checks newPort, and once it is serving traffic,
+// atomically repoints the subdomain's upstream at it and reloads nginx.
+// It returns the port the upstream previously pointed at so the caller can
+// tear down the old container/process only after the swap has succeeded.
+func (n *NginxService) SwitchUpstream(sub string, newPort int) (int, error) {
+	if !n.IsSubdomainAllowed(sub) {
+		return 0, fmt.Errorf("invalid or reserved subdomain: %s", sub)
+	}
+
+	if !n.waitUntilHealthy(newPort, 30*time.Second) {
+		return 0, fmt.Errorf("candidate upstream on port %d never became healthy", newPort)
+	}
+
+	upstreamFile := n.upstreamPath(sub)
+	oldPort := n.currentUpstreamPort(sub)
+
+	tmpFile := upstreamFile + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(n.GenerateUpstream(sub, newPort)), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write candidate upstream: %w", err)
+	}
+	if err := os.Rename(tmpFile, upstreamFile); err != nil {
+		os.Remove(tmpFile)
+		return 0, fmt.Errorf("failed to swap upstream: %w", err)
+	}
+
+	if err := n.Reload(); err != nil {
+		return 0, fmt.Errorf("failed to reload nginx after upstream swap: %w", err)
+	}
+
+	return oldPort, nil
+}
+
+// currentUpstreamPort reads back the port a subdomain's upstream file
+// currently points at, returning 0 if it can't be determined.
+func (n *NginxService) currentUpstreamPort(sub string) int {
+	data, err := os.ReadFile(n.upstreamPath(sub))
+	if err != nil {
+		return 0
+	}
+
+	matches := regexp.MustCompile(`127\.0\.0\.1:(\d+)`).FindStringSubmatch(string(data))
+	if len(matches) < 2 {
+		return 0
+	}
+
+	port, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0
+	}
+	return port
+}
+
+// waitUntilHealthy polls the candidate upstream until it answers with a
+// 2xx/3xx response or the timeout elapses.
+func (n *NginxService) waitUntilHealthy(port int, timeout time.Duration) bool {
+	client := &http.Client{Timeout: 2 * time.Second}
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 400 {
+				return true
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return false
+}
+
 func (n *NginxService) RemoveConfig(sub string) error {
 	available := filepath.Join(nginxAvailableDir, sub+".conf")
 	enabled := filepath.Join(nginxEnabledDir, sub+".conf")
 
 	os.Remove(enabled)
 	os.Remove(available)
+	os.Remove(n.upstreamPath(sub))
 
 	return n.Reload()
 }
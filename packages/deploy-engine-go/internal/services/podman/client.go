@@ -0,0 +1,360 @@
+package podman
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// socketPath returns the rootless Podman API socket, honoring PODMAN_SOCK
+// for setups that expose it somewhere other than the XDG runtime dir
+// default.
+func socketPath() string {
+	if sock := os.Getenv("PODMAN_SOCK"); sock != "" {
+		return sock
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	return filepath.Join(runtimeDir, "podman", "podman.sock")
+}
+
+// Client talks to the Podman REST API over its unix socket. It uses the
+// Docker-compat endpoints ("/containers/...") for container lifecycle,
+// which is enough to share request/response shapes with the Docker
+// backend, and falls back to libpod-specific endpoints ("/libpod/...")
+// only where the compat API doesn't cover what's needed (rename and events).
+type Client struct {
+	http *http.Client
+}
+
+// NewClient dials the Podman socket. Dialing is lazy per-request (unix
+// sockets don't have a connect-time handshake worth failing fast on), so
+// this never itself returns an error - Ping is what surfaces an
+// unreachable daemon.
+func NewClient() (*Client, error) {
+	sock := socketPath()
+	return &Client{
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", sock)
+				},
+			},
+			Timeout: 60 * time.Second,
+		},
+	}, nil
+}
+
+var (
+	defaultClient     *Client
+	defaultClientErr  error
+	defaultClientOnce sync.Once
+)
+
+// getClient returns the process-wide Podman API client, creating it on
+// first use.
+func getClient() (*Client, error) {
+	defaultClientOnce.Do(func() {
+		defaultClient, defaultClientErr = NewClient()
+	})
+	return defaultClient, defaultClientErr
+}
+
+// Podman's Go HTTP client still needs a host in the request URL even
+// though it dials a unix socket; "d" is the same placeholder podman's own
+// bindings use.
+const baseURL = "http://d"
+
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("podman socket request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// Ping checks that the Podman socket is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, "/_ping", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("podman ping returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// createContainerRequest mirrors the subset of the Docker-compat
+// "/containers/create" body this system relies on.
+type createContainerRequest struct {
+	Image        string              `json:"Image"`
+	Env          []string            `json:"Env,omitempty"`
+	WorkingDir   string              `json:"WorkingDir,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig   hostConfig          `json:"HostConfig"`
+}
+
+type hostConfig struct {
+	PortBindings  map[string][]portBinding `json:"PortBindings,omitempty"`
+	RestartPolicy restartPolicy            `json:"RestartPolicy,omitempty"`
+	Memory        int64                    `json:"Memory,omitempty"`
+	NanoCPUs      int64                    `json:"NanoCpus,omitempty"`
+}
+
+type portBinding struct {
+	HostPort string `json:"HostPort"`
+}
+
+type restartPolicy struct {
+	Name string `json:"Name,omitempty"`
+}
+
+// CreateContainer creates (but does not start) a container, returning its
+// ID.
+func (c *Client) CreateContainer(ctx context.Context, name, image string, hostPort, internalPort int, env []string, memoryMB int64) (string, error) {
+	portKey := fmt.Sprintf("%d/tcp", internalPort)
+	body := createContainerRequest{
+		Image: image,
+		Env:   env,
+		ExposedPorts: map[string]struct{}{
+			portKey: {},
+		},
+		HostConfig: hostConfig{
+			PortBindings: map[string][]portBinding{
+				portKey: {{HostPort: strconv.Itoa(hostPort)}},
+			},
+			RestartPolicy: restartPolicy{Name: "unless-stopped"},
+			Memory:        memoryMB * 1024 * 1024,
+		},
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/containers/create?name="+url.QueryEscape(name), body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("podman create container returned %d: %s", resp.StatusCode, readBody(resp))
+	}
+
+	var created struct {
+		Id string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("failed to decode podman create response: %w", err)
+	}
+	return created.Id, nil
+}
+
+// StartContainer starts a previously created container.
+func (c *Client) StartContainer(ctx context.Context, name string) error {
+	resp, err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/start", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified {
+		return fmt.Errorf("podman start container returned %d: %s", resp.StatusCode, readBody(resp))
+	}
+	return nil
+}
+
+// StopContainer stops a running container, giving it timeoutSeconds to
+// exit before Podman sends SIGKILL.
+func (c *Client) StopContainer(ctx context.Context, name string, timeoutSeconds int) error {
+	path := fmt.Sprintf("/containers/%s/stop?t=%d", url.PathEscape(name), timeoutSeconds)
+	resp, err := c.do(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified {
+		return fmt.Errorf("podman stop container returned %d: %s", resp.StatusCode, readBody(resp))
+	}
+	return nil
+}
+
+// RenameContainer renames a container via the libpod-specific rename
+// endpoint; the Docker-compat API doesn't expose one.
+func (c *Client) RenameContainer(ctx context.Context, oldName, newName string) error {
+	path := fmt.Sprintf("/libpod/containers/%s/rename?name=%s", url.PathEscape(oldName), url.QueryEscape(newName))
+	resp, err := c.do(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("podman rename container returned %d: %s", resp.StatusCode, readBody(resp))
+	}
+	return nil
+}
+
+// RemoveContainer removes a container, optionally force-killing it first.
+func (c *Client) RemoveContainer(ctx context.Context, name string, force bool) error {
+	path := fmt.Sprintf("/containers/%s?force=%t", url.PathEscape(name), force)
+	resp, err := c.do(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("podman remove container returned %d: %s", resp.StatusCode, readBody(resp))
+	}
+	return nil
+}
+
+// ContainerState is the subset of "/containers/{name}/json" this system
+// inspects.
+type ContainerState struct {
+	ID    string
+	Name  string
+	State string // created, running, paused, exited, dead
+}
+
+// InspectContainer returns the container's state, or nil if it doesn't
+// exist.
+func (c *Client) InspectContainer(ctx context.Context, name string) *ContainerState {
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var inspect struct {
+		Id    string `json:"Id"`
+		Name  string `json:"Name"`
+		State struct {
+			Status string `json:"Status"`
+		} `json:"State"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return nil
+	}
+	return &ContainerState{ID: inspect.Id, Name: inspect.Name, State: inspect.State.Status}
+}
+
+// ContainerLogs returns the last `tail` lines of a container's combined
+// stdout/stderr.
+func (c *Client) ContainerLogs(ctx context.Context, name string, tail int) (string, error) {
+	path := fmt.Sprintf("/containers/%s/logs?stdout=true&stderr=true&tail=%d", url.PathEscape(name), tail)
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("podman logs returned %d: %s", resp.StatusCode, readBody(resp))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// StreamLogs follows a container's logs, passing each line to onLog until
+// the returned cancel func is called. Podman's compat logs endpoint, like
+// Docker's, multiplexes stdout/stderr frames only for non-TTY containers;
+// every container this system runs is started without a TTY, so the
+// frames are demultiplexed with stdcopy before the line scan.
+func (c *Client) StreamLogs(name string, onLog func(string)) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	path := fmt.Sprintf("/containers/%s/logs?follow=true&stdout=true&stderr=true&tail=0", url.PathEscape(name))
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		cancel()
+		return func() {}
+	}
+
+	go func() {
+		defer resp.Body.Close()
+
+		pr, pw := io.Pipe()
+		go func() {
+			_, _ = stdcopy.StdCopy(pw, pw, resp.Body)
+			pw.Close()
+		}()
+
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			onLog(scanner.Text())
+		}
+	}()
+
+	return cancel
+}
+
+// Event is the subset of a libpod "/events" message this system reacts to.
+type Event struct {
+	Type   string            `json:"Type"`
+	Status string            `json:"Status"`
+	Actor  struct {
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// SubscribeEvents opens a long-lived connection to libpod's "/events"
+// endpoint, filtered to container events, and streams decoded messages to
+// onEvent until ctx is cancelled.
+func (c *Client) SubscribeEvents(ctx context.Context, onEvent func(Event)) {
+	resp, err := c.do(ctx, http.MethodGet, "/libpod/events?stream=true&filters="+url.QueryEscape(`{"type":["container"]}`), nil)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var evt Event
+		if err := dec.Decode(&evt); err != nil {
+			return
+		}
+		onEvent(evt)
+	}
+}
+
+func readBody(resp *http.Response) string {
+	b, _ := io.ReadAll(resp.Body)
+	return string(b)
+}
@@ -0,0 +1,332 @@
+// Package podman implements services.ContainerRuntime against the Podman
+// REST API over its unix socket, so rootless deployments work on hosts
+// that have Podman but no Docker daemon. It mirrors the docker package's
+// naming conventions and control flow so DeployService drives both
+// backends the same way; unlike the Docker backend it does not build
+// images (see Deploy).
+package podman
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/thakurdotdev/deploy-engine/internal/logging"
+)
+
+// PodmanService provides the same high-level deploy API as
+// docker.DockerService, backed by the Podman socket instead of the
+// Docker daemon.
+type PodmanService struct {
+	logStreamers sync.Map // projectID -> cancel func
+}
+
+func NewPodmanService() *PodmanService {
+	return &PodmanService{}
+}
+
+// GetContainerName mirrors docker.GetContainerName's "thakur-{projectId[:8]}"
+// convention, so a project's container is named the same whether it's
+// running under Docker or Podman.
+func GetContainerName(projectID string) string {
+	if len(projectID) > 8 {
+		return "thakur-" + projectID[:8]
+	}
+	return "thakur-" + projectID
+}
+
+func candidateContainerName(projectID string) string {
+	return GetContainerName(projectID) + "-candidate"
+}
+
+const (
+	defaultInternalPort = 3000
+	defaultMemoryMB     = 512
+)
+
+// Deploy starts projectID's container from the image imageFor names for
+// buildID. Unlike the Docker backend, Podman here only runs a prebuilt
+// image - it has no build step and pulls nothing, so that image must
+// already be available to Podman; sourceDir is unused but kept to satisfy
+// services.ContainerRuntime.
+func (p *PodmanService) Deploy(
+	projectID, buildID, sourceDir string,
+	hostPort int,
+	appType string,
+	envVars map[string]string,
+	strategy string,
+) (success bool, containerID string, err error) {
+	if strategy == "blue-green" && p.IsRunning(projectID) {
+		return p.deployBlueGreen(projectID, buildID, hostPort, appType, envVars)
+	}
+	return p.deployRecreate(projectID, buildID, hostPort, appType, envVars)
+}
+
+func (p *PodmanService) deployRecreate(projectID, buildID string, hostPort int, appType string, envVars map[string]string) (bool, string, error) {
+	containerName := GetContainerName(projectID)
+
+	logging.StreamLog(buildID, "Stopping any existing Podman container...", logging.LogLevelInfo)
+	p.Stop(projectID, "")
+
+	containerID, err := p.run(containerName, projectID, buildID, hostPort, appType, envVars)
+	if err != nil {
+		logging.StreamLog(buildID, fmt.Sprintf("Container failed to start: %v", err), logging.LogLevelError)
+		return false, "", err
+	}
+
+	if !p.waitForHealthy(hostPort, 30*time.Second) {
+		logs, _ := p.logs(containerName, 50)
+		logging.StreamLog(buildID, fmt.Sprintf("Container logs:\n%s", logs), logging.LogLevelWarning)
+		logging.StreamLog(buildID, "Health check failed", logging.LogLevelError)
+		p.stopAndRemove(containerName)
+		return false, "", fmt.Errorf("health check failed")
+	}
+
+	logging.StreamLog(buildID, "Container deployed successfully!", logging.LogLevelSuccess)
+	p.StartLogStreaming(projectID, buildID)
+	return true, containerID, nil
+}
+
+// deployBlueGreen mirrors docker.DockerService.deployBlueGreen: it starts
+// the new container under a "-candidate" name alongside the live one and
+// never touches the live container itself. The caller switches traffic
+// and calls PromoteCandidate once this reports success.
+func (p *PodmanService) deployBlueGreen(projectID, buildID string, hostPort int, appType string, envVars map[string]string) (bool, string, error) {
+	candidateName := candidateContainerName(projectID)
+	p.stopAndRemove(candidateName)
+
+	containerID, err := p.run(candidateName, projectID, buildID, hostPort, appType, envVars)
+	if err != nil {
+		logging.StreamLog(buildID, fmt.Sprintf("Candidate container failed to start: %v", err), logging.LogLevelError)
+		return false, "", err
+	}
+
+	if !p.waitForHealthy(hostPort, 30*time.Second) {
+		logs, _ := p.logs(candidateName, 50)
+		logging.StreamLog(buildID, fmt.Sprintf("Candidate logs:\n%s", logs), logging.LogLevelWarning)
+		logging.StreamLog(buildID, "Candidate health check failed, leaving the live container in place", logging.LogLevelError)
+		p.stopAndRemove(candidateName)
+		return false, "", fmt.Errorf("health check failed")
+	}
+
+	logging.StreamLog(buildID, "Candidate container healthy, ready to switch traffic", logging.LogLevelSuccess)
+	return true, containerID, nil
+}
+
+func (p *PodmanService) run(containerName, projectID, buildID string, hostPort int, appType string, envVars map[string]string) (string, error) {
+	cli, err := getClient()
+	if err != nil {
+		return "", fmt.Errorf("podman unavailable: %w", err)
+	}
+
+	internalPort := defaultInternalPort
+	if appType == "vite" {
+		internalPort = 80
+	}
+
+	env := make([]string, 0, len(envVars))
+	for k, v := range envVars {
+		env = append(env, k+"="+v)
+	}
+
+	ctx := context.Background()
+	containerID, err := cli.CreateContainer(ctx, containerName, imageFor(projectID, buildID), hostPort, internalPort, env, defaultMemoryMB)
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+	if err := cli.StartContainer(ctx, containerName); err != nil {
+		return "", fmt.Errorf("failed to start container: %w", err)
+	}
+	return containerID, nil
+}
+
+// imageFor is the image reference Deploy runs: the unqualified
+// "thakur-deploy/{projectId[:8]}:{buildId[:8]}" name the Docker backend
+// tags its builds with. It carries no registry host; config's RegistryURL
+// is not used here.
+func imageFor(projectID, buildID string) string {
+	pid, bid := projectID, buildID
+	if len(pid) > 8 {
+		pid = pid[:8]
+	}
+	if len(bid) > 8 {
+		bid = bid[:8]
+	}
+	return "thakur-deploy/" + pid + ":" + bid
+}
+
+// PromoteCandidate finishes a blue-green cutover: stop the container the
+// candidate is replacing and rename the candidate to the stable name. A
+// no-op (returning true) if there's no candidate.
+func (p *PodmanService) PromoteCandidate(projectID, buildID string) bool {
+	cli, err := getClient()
+	if err != nil {
+		return false
+	}
+
+	stableName := GetContainerName(projectID)
+	candidateName := candidateContainerName(projectID)
+
+	if cli.InspectContainer(context.Background(), candidateName) == nil {
+		return true
+	}
+
+	p.StopLogStreaming(projectID)
+	p.stopAndRemove(stableName)
+
+	if err := cli.RenameContainer(context.Background(), candidateName, stableName); err != nil {
+		logging.StreamLog(buildID, fmt.Sprintf("Traffic switched, but promoting the candidate container failed: %v", err), logging.LogLevelError)
+		return false
+	}
+
+	p.StartLogStreaming(projectID, buildID)
+	return true
+}
+
+// Stop stops a deployed container.
+func (p *PodmanService) Stop(projectID, buildID string) bool {
+	p.StopLogStreaming(projectID)
+	containerName := GetContainerName(projectID)
+
+	if buildID != "" {
+		logging.StreamLog(buildID, "Stopping container...", logging.LogLevelInfo)
+	}
+
+	result := p.stopAndRemove(containerName)
+
+	if buildID != "" && result {
+		logging.StreamLog(buildID, "Container stopped", logging.LogLevelSuccess)
+	}
+	return result
+}
+
+func (p *PodmanService) stopAndRemove(containerName string) bool {
+	cli, err := getClient()
+	if err != nil {
+		return false
+	}
+	ctx := context.Background()
+	cli.StopContainer(ctx, containerName, 10)
+	return cli.RemoveContainer(ctx, containerName, true) == nil
+}
+
+// Cleanup removes the running container for a project. Podman images
+// aren't pruned here since this backend doesn't build or pull them as
+// part of Deploy.
+func (p *PodmanService) Cleanup(projectID string, buildIDs []string) {
+	p.StopLogStreaming(projectID)
+	p.stopAndRemove(GetContainerName(projectID))
+}
+
+// IsRunning checks if a project's container is running.
+func (p *PodmanService) IsRunning(projectID string) bool {
+	cli, err := getClient()
+	if err != nil {
+		return false
+	}
+	state := cli.InspectContainer(context.Background(), GetContainerName(projectID))
+	return state != nil && state.State == "running"
+}
+
+// GetLogs returns the last `tail` lines of a project's container logs.
+func (p *PodmanService) GetLogs(projectID string, tail int) string {
+	logs, _ := p.logs(GetContainerName(projectID), tail)
+	return logs
+}
+
+func (p *PodmanService) logs(containerName string, tail int) (string, error) {
+	cli, err := getClient()
+	if err != nil {
+		return "", err
+	}
+	return cli.ContainerLogs(context.Background(), containerName, tail)
+}
+
+func (p *PodmanService) waitForHealthy(port int, timeout time.Duration) bool {
+	client := &http.Client{Timeout: 2 * time.Second}
+	url := fmt.Sprintf("http://localhost:%d", port)
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 500 {
+				return true
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return false
+}
+
+// StartLogStreaming starts background log streaming to control-api.
+func (p *PodmanService) StartLogStreaming(projectID, buildID string) {
+	p.StopLogStreaming(projectID)
+
+	cli, err := getClient()
+	if err != nil {
+		return
+	}
+
+	cancel := cli.StreamLogs(GetContainerName(projectID), func(line string) {
+		logging.StreamLog(buildID, line, logging.LogLevelInfo)
+	})
+	p.logStreamers.Store(projectID, cancel)
+}
+
+// StopLogStreaming stops log streaming for a project.
+func (p *PodmanService) StopLogStreaming(projectID string) {
+	if cancel, ok := p.logStreamers.Load(projectID); ok {
+		cancel.(func())()
+		p.logStreamers.Delete(projectID)
+	}
+}
+
+// RecoverLogStreams subscribes to Podman's container events on startup and
+// logs every "died" event (a deliberate Stop included) with the
+// container's last 100 log lines. It does not restart log streaming for
+// containers that were already running, and it does not auto-restart
+// exited containers or mark deployments unhealthy.
+func (p *PodmanService) RecoverLogStreams() {
+	cli, err := getClient()
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	go cli.SubscribeEvents(ctx, func(evt Event) {
+		if evt.Status != "died" {
+			return
+		}
+		name := evt.Actor.Attributes["name"]
+		logs, _ := p.logs(name, 100)
+		logging.StreamLog("", fmt.Sprintf("Container %s exited unexpectedly, last logs:\n%s", name, logs), logging.LogLevelError)
+	})
+}
+
+// IsAvailable checks if the Podman socket is reachable.
+func IsAvailable() bool {
+	cli, err := getClient()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return cli.Ping(ctx) == nil
+}
+
+var (
+	defaultPodmanService *PodmanService
+	podmanOnce           sync.Once
+)
+
+// GetPodmanService returns the singleton PodmanService instance.
+func GetPodmanService() *PodmanService {
+	podmanOnce.Do(func() {
+		defaultPodmanService = NewPodmanService()
+	})
+	return defaultPodmanService
+}